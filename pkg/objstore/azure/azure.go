@@ -104,7 +104,7 @@ func NewBucket(logger log.Logger, azureConfig []byte, component string) (*Bucket
 
 // Iter calls f for each entry in the given directory. The argument to f is the full
 // object name including the prefix of the inspected directory.
-func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error) error {
+func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
 
 	prefix := dir
 	if prefix != "" && !strings.HasSuffix(prefix, DirDelim) {
@@ -112,26 +112,35 @@ func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error) err
 	}
 
 	marker := blob.Marker{}
+	params := objstore.ApplyIterOptions(options...)
+	listOptions := blob.ListBlobsSegmentOptions{Prefix: prefix}
 
 	for i := 1; ; i++ {
-		list, err := b.containerURL.ListBlobsHierarchySegment(ctx, marker, DirDelim, blob.ListBlobsSegmentOptions{
-			Prefix: prefix,
-		})
-
-		if err != nil {
-			return errors.Wrapf(err, "cannot list blobs in directory %s (iteration #%d)", dir, i)
-		}
-
-		marker = list.NextMarker
-
 		var listNames []string
 
-		for _, blob := range list.Segment.BlobItems {
-			listNames = append(listNames, blob.Name)
-		}
+		if params.Recursive {
+			list, err := b.containerURL.ListBlobsFlatSegment(ctx, marker, listOptions)
+			if err != nil {
+				return errors.Wrapf(err, "cannot list blobs in directory %s (iteration #%d)", dir, i)
+			}
 
-		for _, blobPrefix := range list.Segment.BlobPrefixes {
-			listNames = append(listNames, blobPrefix.Name)
+			marker = list.NextMarker
+			for _, blob := range list.Segment.BlobItems {
+				listNames = append(listNames, blob.Name)
+			}
+		} else {
+			list, err := b.containerURL.ListBlobsHierarchySegment(ctx, marker, DirDelim, listOptions)
+			if err != nil {
+				return errors.Wrapf(err, "cannot list blobs in directory %s (iteration #%d)", dir, i)
+			}
+
+			marker = list.NextMarker
+			for _, blob := range list.Segment.BlobItems {
+				listNames = append(listNames, blob.Name)
+			}
+			for _, blobPrefix := range list.Segment.BlobPrefixes {
+				listNames = append(listNames, blobPrefix.Name)
+			}
 		}
 
 		for _, name := range listNames {
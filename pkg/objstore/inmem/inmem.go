@@ -37,7 +37,7 @@ func (b *Bucket) Objects() map[string][]byte {
 
 // Iter calls f for each entry in the given directory. The argument to f is the full
 // object name including the prefix of the inspected directory.
-func (b *Bucket) Iter(_ context.Context, dir string, f func(string) error) error {
+func (b *Bucket) Iter(_ context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
 	unique := map[string]struct{}{}
 
 	var dirPartsCount int
@@ -49,12 +49,17 @@ func (b *Bucket) Iter(_ context.Context, dir string, f func(string) error) error
 		dirPartsCount++
 	}
 
+	params := objstore.ApplyIterOptions(options...)
 	b.mtx.RLock()
 	for filename := range b.objects {
 		if !strings.HasPrefix(filename, dir) || dir == filename {
 			continue
 		}
 
+		if params.Recursive {
+			unique[filename] = struct{}{}
+			continue
+		}
 		parts := strings.SplitAfter(filename, objstore.DirDelim)
 		unique[strings.Join(parts[:dirPartsCount+1], "")] = struct{}{}
 	}
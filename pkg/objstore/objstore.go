@@ -38,9 +38,9 @@ type Bucket interface {
 
 // BucketReader provides read access to an object storage bucket.
 type BucketReader interface {
-	// Iter calls f for each entry in the given directory (not recursive.). The argument to f is the full
-	// object name including the prefix of the inspected directory.
-	Iter(ctx context.Context, dir string, f func(string) error) error
+	// Iter calls f for each entry in the given directory (not recursive unless WithRecursiveIter is given).
+	// The argument to f is the full object name including the prefix of the inspected directory.
+	Iter(ctx context.Context, dir string, f func(string) error, options ...IterOption) error
 
 	// Get returns a reader for the given object name.
 	Get(ctx context.Context, name string) (io.ReadCloser, error)
@@ -56,6 +56,57 @@ type BucketReader interface {
 	IsObjNotFoundErr(err error) bool
 }
 
+// IterOption configures the provided params.
+type IterOption func(params *IterParams)
+
+// WithRecursiveIter is an option that can be applied to Iter() to recursively list objects
+// in the bucket.
+func WithRecursiveIter(params *IterParams) {
+	params.Recursive = true
+}
+
+// IterParams holds the Iter() parameters and is used by objstore clients implementations.
+type IterParams struct {
+	// Recursive makes Iter list all objects under the directory, in subdirectories too, instead of only its
+	// direct entries. No directory entries are passed to f then.
+	Recursive bool
+}
+
+// ApplyIterOptions returns the parameters set by the options.
+func ApplyIterOptions(options ...IterOption) IterParams {
+	out := IterParams{}
+	for _, opt := range options {
+		opt(&out)
+	}
+	return out
+}
+
+// ObjectAttributes holds the attributes of an object.
+type ObjectAttributes struct {
+	// Size is the object size in bytes.
+	Size int64
+	// LastModified is the time the object was last modified.
+	LastModified time.Time
+	// Created is the time the object was created, where known apart from LastModified, e.g. as stamped by
+	// the uploader. It is the zero time otherwise.
+	Created time.Time
+}
+
+// IterObjectAttributes holds the name of an entry passed to the callback of IterWithAttributes, along
+// with the attributes of the object as returned by the listing. Directory entries have zero attributes.
+type IterObjectAttributes struct {
+	Name string
+	ObjectAttributes
+}
+
+// AttributesIterator is implemented by buckets able to return the attributes of objects while listing
+// them, saving a request per object to fetch them.
+type AttributesIterator interface {
+	// IterWithAttributes calls f for each entry in the given directory like Iter, passing the
+	// attributes of the object along with its name.
+	IterWithAttributes(ctx context.Context, dir string, f func(IterObjectAttributes) error, options ...IterOption) error
+}
+
 // UploadDir uploads all files in srcdir to the bucket with into a top-level directory
 // named dstdir. It is a caller responsibility to clean partial upload in case of failure.
 func UploadDir(ctx context.Context, logger log.Logger, bkt Bucket, srcdir, dstdir string) error {
@@ -226,10 +277,10 @@ type metricBucket struct {
 	lastSuccessfullUploadTime *prometheus.GaugeVec
 }
 
-func (b *metricBucket) Iter(ctx context.Context, dir string, f func(name string) error) error {
+func (b *metricBucket) Iter(ctx context.Context, dir string, f func(name string) error, options ...IterOption) error {
 	const op = "iter"
 
-	err := b.bkt.Iter(ctx, dir, f)
+	err := b.bkt.Iter(ctx, dir, f, options...)
 	if err != nil {
 		b.opsFailures.WithLabelValues(op).Inc()
 	}
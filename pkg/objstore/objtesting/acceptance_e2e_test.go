@@ -108,5 +108,35 @@ func TestObjStore_AcceptanceTest_e2e(t *testing.T) {
 			return nil
 		}))
 		testutil.Equals(t, []string{"id1/obj_1.some", "id1/obj_3.some"}, seen)
+
+		testutil.Ok(t, bkt.Upload(ctx, "id2/id3/obj_6.some", strings.NewReader("@test-data6@")))
+
+		// Can we iter over items from id2 dir without descending into its subdirectories?
+		seen = []string{}
+		testutil.Ok(t, bkt.Iter(ctx, "id2", func(fn string) error {
+			seen = append(seen, fn)
+			return nil
+		}))
+		sort.Strings(seen)
+		testutil.Equals(t, []string{"id2/id3/", "id2/obj_4.some"}, seen)
+
+		// Can we iter over all items recursively?
+		seen = []string{}
+		testutil.Ok(t, bkt.Iter(ctx, "", func(fn string) error {
+			seen = append(seen, fn)
+			return nil
+		}, objstore.WithRecursiveIter))
+		expected = []string{"id1/obj_1.some", "id1/obj_3.some", "id2/id3/obj_6.some", "id2/obj_4.some", "obj_5.some"}
+		sort.Strings(seen)
+		testutil.Equals(t, expected, seen)
+
+		// Can we iter over items from id2 dir recursively?
+		seen = []string{}
+		testutil.Ok(t, bkt.Iter(ctx, "id2/", func(fn string) error {
+			seen = append(seen, fn)
+			return nil
+		}, objstore.WithRecursiveIter))
+		sort.Strings(seen)
+		testutil.Equals(t, []string{"id2/id3/obj_6.some", "id2/obj_4.some"}, seen)
 	})
 }
@@ -0,0 +1,57 @@
+package oss
+
+import (
+	"context"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// Supported values of Config.ObjectACL and SetObjectACL.
+const (
+	ObjectACLPrivate         = string(alioss.ACLPrivate)
+	ObjectACLPublicRead      = string(alioss.ACLPublicRead)
+	ObjectACLPublicReadWrite = string(alioss.ACLPublicReadWrite)
+	// ObjectACLDefault makes the object inherit the ACL of the bucket.
+	ObjectACLDefault = string(alioss.ACLDefault)
+)
+
+func validateObjectACL(acl string) error {
+	switch acl {
+	case ObjectACLPrivate, ObjectACLPublicRead, ObjectACLPublicReadWrite, ObjectACLDefault:
+		return nil
+	}
+	return errors.Errorf("unsupported object ACL %q, expected one of %s, %s, %s or %s",
+		acl, ObjectACLPrivate, ObjectACLPublicRead, ObjectACLPublicReadWrite, ObjectACLDefault)
+}
+
+// SetObjectACL replaces the ACL of the object with the given canned ACL.
+func (b *Bucket) SetObjectACL(ctx context.Context, name, acl string) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateObjectACL(acl); err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	err = b.retry(ctx, func() error { return b.bucket.SetObjectACL(name, alioss.ACLType(acl)) })
+	if err != nil {
+		err = withClockSkewHint(errors.Wrapf(err, "set ACL of oss object %s", name))
+	}
+	b.audit(OpSetACL, name, 0, start, err)
+	return err
+}
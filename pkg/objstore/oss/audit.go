@@ -0,0 +1,69 @@
+package oss
+
+import (
+	"time"
+)
+
+// Mutating operations reported to an Auditor.
+const (
+	OpUpload = "upload"
+	OpDelete = "delete"
+	// OpRestore starts restoring an archived object, see RestoreObject.
+	OpRestore = "restore"
+	// OpPutTagging replaces the tags of an object, see PutObjectTagging.
+	OpPutTagging = "put_tagging"
+	// OpAbortMultipartUpload aborts an incomplete multipart upload, see AbortIncompleteMultipartUploads.
+	OpAbortMultipartUpload = "abort_multipart_upload"
+	// OpSetACL replaces the ACL of an object, see SetObjectACL.
+	OpSetACL = "set_acl"
+	// OpPutSymlink points a symlink to a new target, see SwapSymlink.
+	OpPutSymlink = "put_symlink"
+	// OpCreateBucket creates the missing bucket, see EnsureBucket.
+	OpCreateBucket = "create_bucket"
+)
+
+// AuditEvent describes a single mutating operation performed on the bucket.
+type AuditEvent struct {
+	// Operation is one of the Op* constants.
+	Operation string
+	Bucket    string
+	Key       string
+	// Component and AccessKeyID identify who performed the operation.
+	Component   string
+	AccessKeyID string
+	// Bytes is the number of bytes written by a successful upload.
+	Bytes    int64
+	Start    time.Time
+	Duration time.Duration
+	// Err is the result of the operation, nil on success.
+	Err error
+}
+
+// Auditor records mutating bucket operations, e.g. into an audit log. Audit is called synchronously
+// after each operation completes, so implementations should not block.
+type Auditor interface {
+	Audit(AuditEvent)
+}
+
+func (b *Bucket) audit(op, key string, n int64, start time.Time, err error) {
+	if b.auditor == nil {
+		return
+	}
+	b.auditor.Audit(AuditEvent{
+		Operation:   op,
+		Bucket:      b.name,
+		Key:         key,
+		Component:   b.component,
+		AccessKeyID: b.accessKeyID(),
+		Bytes:       n,
+		Start:       start,
+		Duration:    time.Since(start),
+		Err:         err,
+	})
+}
+
+// accessKeyID returns the ID of the access key the client signs requests with, which is that of the
+// credentials provider, if any, rather than the configured one.
+func (b *Bucket) accessKeyID() string {
+	return b.client.Config.GetCredentials().GetAccessKeyID()
+}
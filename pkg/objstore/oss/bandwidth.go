@@ -0,0 +1,66 @@
+package oss
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// newBandwidthLimiter returns a token bucket refilled with limit bytes per second and holding up to a
+// second of them, or nil if limit is zero.
+func newBandwidthLimiter(limit int64) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit), int(limit))
+}
+
+// rateLimitedReader waits for a token per byte read through it before returning the bytes, reading at
+// most the token bucket's burst at once. Waiting stops with the error of ctx once it is done.
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b := r.limiter.Burst(); len(p) > b {
+		p = p[:b]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// rateLimitedReadCloser is a rateLimitedReader closing the underlying reader.
+type rateLimitedReadCloser struct {
+	rateLimitedReader
+	io.Closer
+}
+
+// limitUpload caps the bandwidth of the upload body r to upload_bandwidth_limit, shared by all uploads of
+// the Bucket.
+func (b *Bucket) limitUpload(ctx context.Context, r io.Reader) io.Reader {
+	if b.uploadLimiter == nil {
+		return r
+	}
+	return &rateLimitedReader{ctx: ctx, r: r, limiter: b.uploadLimiter}
+}
+
+// downloadReader wraps the body of a download to stop reading once ctx is done, to cap its bandwidth to
+// download_bandwidth_limit, shared by all downloads of the Bucket, and to count the bytes read.
+func (b *Bucket) downloadReader(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	rc = newContextReader(ctx, rc)
+	if b.downloadLimiter != nil {
+		rc = &rateLimitedReadCloser{rateLimitedReader: rateLimitedReader{ctx: ctx, r: rc, limiter: b.downloadLimiter}, Closer: rc}
+	}
+	return &countingReadCloser{ReadCloser: rc, counter: b.metrics.downloadedBytes}
+}
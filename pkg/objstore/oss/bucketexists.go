@@ -0,0 +1,67 @@
+package oss
+
+import (
+	"context"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// BucketExists returns whether the bucket exists. It gets the bucket info, which only needs access to the
+// bucket itself rather than to the listing of all buckets of the account, falling back to listing a single
+// object on endpoints without GetBucketInfo.
+func (b *Bucket) BucketExists(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	err = b.retry(ctx, func() error {
+		_, err := b.client.GetBucketInfo(b.name)
+		return err
+	})
+	if isUnsupportedErr(err) {
+		err = b.retryList(ctx, func() error {
+			_, err := b.bucket.ListObjects(alioss.MaxKeys(1))
+			return err
+		})
+	}
+	switch {
+	case err == nil:
+		return true, nil
+	case IsNoSuchBucketErr(err):
+		return false, nil
+	}
+	return false, withClockSkewHint(errors.Wrapf(err, "check existence of oss bucket %s", b.name))
+}
+
+// EnsureBucket creates the bucket unless it exists. Creating buckets must be allowed with create_bucket,
+// otherwise a missing bucket is reported as an error, so that a mistyped bucket name is never created.
+func (b *Bucket) EnsureBucket(ctx context.Context) error {
+	ok, err := b.BucketExists(ctx)
+	if err != nil || ok {
+		return err
+	}
+	if !b.config.CreateBucket {
+		return errors.Errorf("oss bucket %s does not exist, set create_bucket to create it", b.name)
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	level.Info(b.logger).Log("msg", "creating missing oss bucket", "bucket", b.name)
+	start := time.Now()
+	err = b.retry(ctx, func() error { return b.client.CreateBucket(b.name) })
+	if err != nil {
+		err = withClockSkewHint(errors.Wrapf(err, "create oss bucket %s", b.name))
+	}
+	b.audit(OpCreateBucket, "", 0, start, err)
+	return err
+}
@@ -0,0 +1,77 @@
+package oss
+
+import (
+	"math"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/simplelru"
+)
+
+type cachedObject struct {
+	etag string
+	data []byte
+}
+
+// objectCache is a thread-safe LRU cache of whole small objects bounded by their total size in bytes.
+type objectCache struct {
+	mtx     sync.Mutex
+	lru     *lru.LRU
+	curSize int64
+	maxSize int64
+}
+
+func newObjectCache(maxSize int64) (*objectCache, error) {
+	c := &objectCache{maxSize: maxSize}
+	l, err := lru.NewLRU(math.MaxInt64, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+	return c, nil
+}
+
+func (c *objectCache) onEvict(_, val interface{}) {
+	c.curSize -= int64(len(val.(cachedObject).data))
+}
+
+// get returns the cached content of the object if it is still at the given ETag.
+func (c *objectCache) get(name, etag string) ([]byte, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	v, ok := c.lru.Get(name)
+	if !ok {
+		return nil, false
+	}
+	if o := v.(cachedObject); o.etag == etag {
+		return o.data, true
+	}
+	c.lru.Remove(name)
+	return nil, false
+}
+
+func (c *objectCache) set(name, etag string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxSize {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lru.Remove(name)
+	for c.curSize+size > c.maxSize {
+		if _, _, ok := c.lru.RemoveOldest(); !ok {
+			return
+		}
+	}
+	c.lru.Add(name, cachedObject{etag: etag, data: data})
+	c.curSize += size
+}
+
+func (c *objectCache) remove(name string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lru.Remove(name)
+}
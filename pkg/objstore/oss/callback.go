@@ -0,0 +1,139 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// OSS accepts at most 5 callback URLs per request.
+const maxCallbackURLs = 5
+
+// Callback configures an OSS upload callback. Once the upload completes, OSS sends Body to the callback
+// server and relays the server's response back to the uploader. See
+// https://www.alibabacloud.com/help/doc-detail/31989.htm for the supported body variables.
+type Callback struct {
+	// URL of the callback server. Up to 5 URLs may be given, separated by ';'.
+	URL string `json:"callbackUrl"`
+	// Host is the Host header sent to the callback server. Defaults to the host of URL.
+	Host string `json:"callbackHost,omitempty"`
+	// Body sent to the callback server, e.g. "bucket=${bucket}&object=${object}".
+	Body string `json:"callbackBody"`
+	// BodyType is either "application/x-www-form-urlencoded" (the default) or "application/json".
+	BodyType string `json:"callbackBodyType,omitempty"`
+}
+
+func (c Callback) validate() error {
+	if c.URL == "" {
+		return errors.New("callback URL must be set")
+	}
+	urls := strings.Split(c.URL, ";")
+	if len(urls) > maxCallbackURLs {
+		return errors.Errorf("at most %d callback URLs are allowed, got %d", maxCallbackURLs, len(urls))
+	}
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return errors.Wrapf(err, "parse callback URL %q", u)
+		}
+		if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.Errorf("callback URL %q must be an absolute http or https URL", u)
+		}
+	}
+	if c.Body == "" {
+		return errors.New("callback body must be set")
+	}
+	switch c.BodyType {
+	case "", "application/x-www-form-urlencoded", "application/json":
+	default:
+		return errors.Errorf("unsupported callback body type %q", c.BodyType)
+	}
+	return nil
+}
+
+// encode returns the value of the x-oss-callback header.
+func (c Callback) encode() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// UploadWithCallback uploads the contents of the reader like Upload, asking OSS to invoke the given
+// callback once the object is written. It returns the response of the callback server.
+// The callback is attached only to the request completing the upload, i.e. PutObject for single part
+// uploads and CompleteMultipartUpload for multipart ones.
+func (b *Bucket) UploadWithCallback(ctx context.Context, name string, r io.Reader, cb Callback) ([]byte, error) {
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return nil, err
+	}
+	if err := cb.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid oss callback")
+	}
+	encoded, err := cb.encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "encode oss callback")
+	}
+
+	p := &uploadParams{callback: encoded}
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, p)
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return p.callbackResponse, nil
+}
+
+// completeMultipartUploadXML mirrors the SDK's unexported request body of CompleteMultipartUpload.
+type completeMultipartUploadXML struct {
+	XMLName xml.Name            `xml:"CompleteMultipartUpload"`
+	Parts   []alioss.UploadPart `xml:"Part"`
+}
+
+// completeMultipartUploadWithCallback completes the multipart upload with a callback. The SDK expects an
+// XML result from CompleteMultipartUpload, but OSS returns the callback server's response instead, so the
+// request is sent through the lower level connection.
+func (b *Bucket) completeMultipartUploadWithCallback(init alioss.InitiateMultipartUploadResult, parts []alioss.UploadPart, p *uploadParams) error {
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	body, err := xml.Marshal(completeMultipartUploadXML{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	headers := p.conditionHeaders()
+	headers[alioss.HTTPHeaderOssCallback] = p.callback
+	resp, err := b.client.Conn.Do(
+		"POST", b.name, init.Key,
+		map[string]interface{}{"uploadId": init.UploadID},
+		headers,
+		bytes.NewReader(body), 0, nil,
+	)
+	if resp != nil {
+		defer runutil.CloseWithLogOnErr(b.logger, resp.Body, "oss complete multipart upload response body")
+	}
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d completing multipart upload with callback", resp.StatusCode)
+	}
+	p.callbackResponse, err = ioutil.ReadAll(resp.Body)
+	return errors.Wrap(err, "read callback response")
+}
@@ -0,0 +1,26 @@
+package oss
+
+import (
+	"context"
+	"io"
+)
+
+// acquire waits for one of the max_concurrent_requests slots of the Bucket and returns the function
+// releasing it, or the error of ctx if it is done first. It never waits without a limit configured.
+func (b *Bucket) acquire(ctx context.Context) (func(), error) {
+	if b.sem == nil {
+		return func() {}, nil
+	}
+	if err := b.sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { b.sem.Release(1) }, nil
+}
+
+// releaseOnClose returns rc releasing the slot acquired for the read returning it once closed.
+func (b *Bucket) releaseOnClose(rc io.ReadCloser, release func()) io.ReadCloser {
+	if b.sem == nil {
+		return rc
+	}
+	return &releasingBody{ReadCloser: rc, release: release}
+}
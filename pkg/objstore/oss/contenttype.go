@@ -0,0 +1,48 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"mime"
+	"path"
+	"time"
+)
+
+// contentTypes pins the content type of the files Thanos writes, which mime.TypeByExtension would
+// otherwise take from the MIME tables of the host, if it knows them at all.
+var contentTypes = map[string]string{
+	".json": "application/json",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".gz":   "application/gzip",
+}
+
+const defaultContentType = "application/octet-stream"
+
+// contentType returns the content type of an object with the given name, detected from its extension.
+// Blocks' index and chunk files have none and are stored as application/octet-stream.
+func contentType(name string) string {
+	ext := path.Ext(name)
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return defaultContentType
+}
+
+// UploadWithContentType is Upload storing the object with the given content type rather than the one
+// detected from its name.
+func (b *Bucket) UploadWithContentType(ctx context.Context, name string, r io.Reader, contentType string) error {
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, &uploadParams{contentType: contentType})
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	return err
+}
@@ -0,0 +1,128 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// MaxCopyObjectSize is the size of the largest object OSS copies with a single CopyObject request. Larger
+// objects are copied part by part.
+const MaxCopyObjectSize = 1024 * 1024 * 1024
+
+// Copy copies the object srcName to dstName within the bucket, without downloading it. The copy keeps the
+// metadata, content type and storage class of the source, and is encrypted as set by sse_config.
+func (b *Bucket) Copy(ctx context.Context, srcName, dstName string) error {
+	srcName, dstName = b.physical(srcName), b.physical(dstName)
+	if err := b.checkKey(srcName); err != nil {
+		return err
+	}
+	if err := b.checkKey(dstName); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	size, err := b.copy(ctx, srcName, dstName)
+	b.forget(dstName)
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, dstName, size, start, err)
+	return err
+}
+
+func (b *Bucket) copy(ctx context.Context, srcName, dstName string) (int64, error) {
+	var header http.Header
+	err := b.retry(ctx, func() (err error) {
+		header, err = b.bucket.GetObjectDetailedMeta(srcName)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "get oss object meta %s", srcName)
+	}
+	size, err := strconv.ParseInt(header.Get(alioss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse size of oss object %s", srcName)
+	}
+
+	// Unlike the metadata, the storage class of the copy is the one of the bucket unless set.
+	opts := b.config.SSEConfig.options()
+	if class := header.Get(alioss.HTTPHeaderOssStorageClass); class != "" {
+		opts = append(opts, alioss.ObjectStorageClass(alioss.StorageClassType(class)))
+	}
+	if size <= b.maxCopySize {
+		err := b.retry(ctx, func() error {
+			_, err := b.bucket.CopyObject(srcName, dstName, opts...)
+			return err
+		})
+		return size, errors.Wrapf(err, "copy oss object %s to %s", srcName, dstName)
+	}
+
+	// Multipart uploads do not take the metadata from the source, it is set when initiating them.
+	if ct := header.Get(alioss.HTTPHeaderContentType); ct != "" {
+		opts = append(opts, alioss.ContentType(ct))
+	}
+	for k, v := range header {
+		if strings.HasPrefix(k, alioss.HTTPHeaderOssMetaPrefix) && len(v) > 0 {
+			opts = append(opts, alioss.Meta(strings.TrimPrefix(k, alioss.HTTPHeaderOssMetaPrefix), v[0]))
+		}
+	}
+	return size, b.copyParts(ctx, srcName, dstName, size, opts)
+}
+
+// copyParts copies the object with UploadPartCopy requests, which copy up to 5GiB each.
+func (b *Bucket) copyParts(ctx context.Context, srcName, dstName string, size int64, opts []alioss.Option) error {
+	partSize, err := b.partSizeFor(dstName, size)
+	if err != nil {
+		return err
+	}
+	init, err := b.bucket.InitiateMultipartUpload(dstName, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to initiate multi-part copy")
+	}
+	abort := func(err error, msg string) error {
+		if aerr := b.bucket.AbortMultipartUpload(init); aerr != nil {
+			level.Warn(b.logger).Log("msg", "failed to abort multi-part copy", "object", dstName, "upload_id", init.UploadID, "err", aerr)
+		}
+		return errors.Wrap(err, msg)
+	}
+
+	var parts []alioss.UploadPart
+	for off, number := int64(0), 1; off < size; off, number = off+partSize, number+1 {
+		if err := ctx.Err(); err != nil {
+			return abort(err, "multi-part copy cancelled")
+		}
+		n := partSize
+		if off+n > size {
+			n = size - off
+		}
+		var part alioss.UploadPart
+		err := b.retry(ctx, func() (err error) {
+			part, err = b.bucket.UploadPartCopy(init, b.name, srcName, off, n, number)
+			return err
+		})
+		if err != nil {
+			return abort(err, "failed to copy multi-part chunk")
+		}
+		parts = append(parts, part)
+	}
+	if _, err := b.bucket.CompleteMultipartUpload(init, parts); err != nil {
+		return abort(err, "failed to complete multi-part copy")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package oss
+
+import (
+	"context"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"strconv"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// crc64Table is the table of the CRC64 OSS computes, reported in the x-oss-hash-crc64ecma header.
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// seekableCRC64 returns the CRC64 of the next size bytes of r, leaving r at its current offset.
+func seekableCRC64(r io.ReadSeeker, size int64) (uint64, error) {
+	h := crc64.New(crc64Table)
+	n, err := io.CopyN(h, r, size)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(-n, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
+
+// combineCRC64 returns the CRC64 of consecutive parts given their CRC64 and sizes.
+func combineCRC64(crcs []uint64, sizes []int64) uint64 {
+	var crc uint64
+	for i := range crcs {
+		crc = alioss.CRC64Combine(crc, crcs[i], uint64(sizes[i]))
+	}
+	return crc
+}
+
+// verifyCRC64 checks the CRC64 OSS computed of the uploaded object against the one computed locally of
+// the uploaded content, if enabled. The SDK checks single requests, but not the object assembled by
+// completing a multipart upload, nor responses lacking the checksum.
+func (b *Bucket) verifyCRC64(ctx context.Context, name string, want uint64) error {
+	if !b.config.EnableCRCValidation {
+		return nil
+	}
+	var header http.Header
+	err := b.retry(ctx, func() (err error) {
+		header, err = b.bucket.GetObjectDetailedMeta(name)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "get oss object meta %s", name)
+	}
+	v := header.Get(alioss.HTTPHeaderOssCRC64)
+	if v == "" {
+		return errors.Errorf("oss reports no CRC64 of uploaded object %s", name)
+	}
+	got, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "parse CRC64 of oss object %s", name)
+	}
+	if got != want {
+		return errors.Wrapf(errChecksumMismatch, "uploaded oss object %s has CRC64 %d, but %d of the content was computed locally", name, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"gopkg.in/yaml.v2"
+)
+
+// Defaults of the credentials refresh settings.
+const (
+	defaultCredentialsRefreshInterval = time.Minute
+	defaultCredentialsExpirySkew      = 5 * time.Minute
+)
+
+// Credentials are OSS access credentials. Temporary STS credentials carry a security token and expire.
+type Credentials struct {
+	AccessKeyID     string `yaml:"access_key_id"`
+	AccessKeySecret string `yaml:"access_key_secret"`
+	SecurityToken   string `yaml:"security_token"`
+	// Expiration is when the credentials expire, the zero time if unknown.
+	Expiration time.Time `yaml:"expiration"`
+}
+
+// CredentialsProvider returns the current credentials, e.g. by assuming a RAM role.
+type CredentialsProvider func(ctx context.Context) (Credentials, error)
+
+// WithCredentialsProvider makes the Bucket sign requests with the credentials returned by p, renewed ahead
+// of their expiration, instead of the static credentials of the config.
+func WithCredentialsProvider(p CredentialsProvider) Option {
+	return optionFunc(func(b *Bucket) {
+		b.credentialsProvider = p
+	})
+}
+
+// fileCredentialsProvider reads the credentials from a YAML or JSON file with the fields of Credentials,
+// e.g. kept up to date by a sidecar.
+func fileCredentialsProvider(path string) CredentialsProvider {
+	return func(context.Context) (Credentials, error) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Credentials{}, errors.Wrap(err, "read credentials file")
+		}
+		var c Credentials
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return Credentials{}, errors.Wrapf(err, "parse credentials file %s", path)
+		}
+		return c, nil
+	}
+}
+
+// Base URL of the RAM role credentials of the ECS instance metadata service.
+const ecsRAMRoleCredentialsURL = "http://100.100.100.100/latest/meta-data/ram/security-credentials/"
+
+// ecsRAMRoleCredentialsProvider fetches the temporary credentials of the RAM role bound to the ECS instance
+// from the metadata service at baseURL, through the given transport or the default one if nil.
+func ecsRAMRoleCredentialsProvider(baseURL, role string, transport http.RoundTripper) CredentialsProvider {
+	client := &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	return func(ctx context.Context) (Credentials, error) {
+		req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/"+url.PathEscape(role), nil)
+		if err != nil {
+			return Credentials{}, err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return Credentials{}, errors.Wrapf(err, "get credentials of ram role %s", role)
+		}
+		defer runutil.ExhaustCloseWithLogOnErr(log.NewNopLogger(), resp.Body, "ecs metadata response body")
+		if resp.StatusCode != http.StatusOK {
+			return Credentials{}, errors.Errorf("get credentials of ram role %s: unexpected status %s", role, resp.Status)
+		}
+
+		var res struct {
+			Code            string
+			AccessKeyID     string `json:"AccessKeyId"`
+			AccessKeySecret string
+			SecurityToken   string
+			Expiration      time.Time
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return Credentials{}, errors.Wrapf(err, "decode credentials of ram role %s", role)
+		}
+		if res.Code != "Success" {
+			return Credentials{}, errors.Errorf("get credentials of ram role %s: metadata service returned code %q", role, res.Code)
+		}
+		return Credentials{
+			AccessKeyID:     res.AccessKeyID,
+			AccessKeySecret: res.AccessKeySecret,
+			SecurityToken:   res.SecurityToken,
+			Expiration:      res.Expiration,
+		}, nil
+	}
+}
+
+// refreshingCredentials holds the credentials requests are signed with, implementing the credentials
+// provider of the SDK. A background goroutine renews them once they expire within the skew.
+type refreshingCredentials struct {
+	logger   log.Logger
+	provider CredentialsProvider
+	interval time.Duration
+	skew     time.Duration
+
+	mtx     sync.RWMutex
+	current Credentials
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRefreshingCredentials(logger log.Logger, provider CredentialsProvider, interval, skew time.Duration) (*refreshingCredentials, error) {
+	if interval <= 0 {
+		interval = defaultCredentialsRefreshInterval
+	}
+	if skew <= 0 {
+		skew = defaultCredentialsExpirySkew
+	}
+	r := &refreshingCredentials{logger: logger, provider: provider, interval: interval, skew: skew, done: make(chan struct{})}
+	c, err := r.fetch(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "get initial aliyun oss credentials")
+	}
+	r.current = c
+	return r, nil
+}
+
+func (r *refreshingCredentials) fetch(ctx context.Context) (Credentials, error) {
+	c, err := r.provider(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if c.AccessKeyID == "" || c.AccessKeySecret == "" {
+		return Credentials{}, errors.New("credentials lack access_key_id or access_key_secret")
+	}
+	return c, nil
+}
+
+// GetCredentials implements alioss.CredentialsProvider.
+func (r *refreshingCredentials) GetCredentials() alioss.Credentials {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return sdkCredentials(r.current)
+}
+
+// start renews the credentials in the background until stop is called. Credentials without expiration
+// are fetched again on every interval.
+func (r *refreshingCredentials) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go func() {
+		defer close(r.done)
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			r.mtx.RLock()
+			expiration := r.current.Expiration
+			r.mtx.RUnlock()
+			if !expiration.IsZero() && time.Until(expiration) > r.skew {
+				continue
+			}
+
+			c, err := r.fetch(ctx)
+			if err != nil {
+				level.Warn(r.logger).Log("msg", "failed to refresh aliyun oss credentials, keeping the current ones", "expiration", expiration, "err", err)
+				continue
+			}
+			r.mtx.Lock()
+			r.current = c
+			r.mtx.Unlock()
+			level.Debug(r.logger).Log("msg", "rotated aliyun oss credentials", "access_key_id", c.AccessKeyID, "expiration", c.Expiration)
+		}
+	}()
+}
+
+func (r *refreshingCredentials) stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// sdkCredentials adapts Credentials to alioss.Credentials.
+type sdkCredentials Credentials
+
+func (c sdkCredentials) GetAccessKeyID() string     { return c.AccessKeyID }
+func (c sdkCredentials) GetAccessKeySecret() string { return c.AccessKeySecret }
+func (c sdkCredentials) GetSecurityToken() string   { return c.SecurityToken }
@@ -0,0 +1,50 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// contextReader fails reads with the context error once the context is done. The SDK cannot cancel
+// requests, so the underlying reader is closed as soon as the context ends, unblocking a read waiting
+// for data from OSS.
+type contextReader struct {
+	ctx  context.Context
+	rc   io.ReadCloser
+	stop chan struct{}
+	once sync.Once
+}
+
+func newContextReader(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	if ctx.Done() == nil {
+		return rc
+	}
+	r := &contextReader{ctx: ctx, rc: rc, stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = rc.Close()
+		case <-r.stop:
+		}
+	}()
+	return r
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.rc.Read(p)
+	if err != nil && err != io.EOF {
+		if cerr := r.ctx.Err(); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+func (r *contextReader) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	return r.rc.Close()
+}
@@ -0,0 +1,87 @@
+package oss
+
+import (
+	"context"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+	terrors "github.com/prometheus/prometheus/tsdb/errors"
+)
+
+// Maximum number of objects OSS deletes with a single DeleteObjects request.
+const maxDeleteObjects = 1000
+
+// DeleteMultiple removes the objects with the given names, sending up to 1000 of them per request.
+// Unlike with Delete, missing objects are not an error, as OSS reports them deleted. The returned error
+// lists every object that was not deleted. Cancelling ctx stops sending further requests. With
+// protected_tag_key configured, the tags of every object are checked with a request of its own first,
+// before any object is deleted.
+func (b *Bucket) DeleteMultiple(ctx context.Context, names []string) error {
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	var errs terrors.MultiError
+	keys := make([]string, 0, len(names))
+	for i, name := range names {
+		if err := ctx.Err(); err != nil {
+			errs.Add(errors.Wrapf(err, "%d oss objects not deleted", len(names)-i))
+			return errs.Err()
+		}
+		name = b.physical(name)
+		if err := b.checkKey(name); err != nil {
+			errs.Add(err)
+			continue
+		}
+		if err := b.checkProtected(ctx, name); err != nil {
+			b.audit(OpDelete, name, 0, time.Now(), err)
+			errs.Add(err)
+			continue
+		}
+		keys = append(keys, name)
+	}
+
+	for len(keys) > 0 {
+		if err := ctx.Err(); err != nil {
+			errs.Add(errors.Wrapf(err, "%d oss objects not deleted", len(keys)))
+			break
+		}
+		batch := keys
+		if len(batch) > maxDeleteObjects {
+			batch = batch[:maxDeleteObjects]
+		}
+
+		release, err := b.acquire(ctx)
+		if err != nil {
+			errs.Add(errors.Wrapf(err, "%d oss objects not deleted", len(keys)))
+			break
+		}
+		keys = keys[len(batch):]
+
+		start := time.Now()
+		var res alioss.DeleteObjectsResult
+		err = b.retry(ctx, func() (err error) {
+			res, err = b.bucket.DeleteObjects(batch)
+			return err
+		})
+		release()
+		if err != nil {
+			err = withClockSkewHint(errors.Wrapf(err, "delete %d oss objects from %s to %s", len(batch), batch[0], batch[len(batch)-1]))
+			errs.Add(err)
+		}
+		deleted := make(map[string]bool, len(res.DeletedObjects))
+		for _, key := range res.DeletedObjects {
+			deleted[key] = true
+		}
+		for _, key := range batch {
+			b.forget(key)
+			keyErr := err
+			if keyErr == nil && !deleted[key] {
+				keyErr = errors.Errorf("oss object %s was not deleted", key)
+				errs.Add(keyErr)
+			}
+			b.audit(OpDelete, key, 0, start, keyErr)
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,99 @@
+package oss
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// regionPattern matches Alibaba Cloud region IDs, e.g. cn-hangzhou or ap-southeast-1.
+	regionPattern = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)+$`)
+	// publicEndpointPattern matches OSS endpoints of a region, public or internal.
+	publicEndpointPattern = regexp.MustCompile(`^oss-([a-z0-9-]+?)(-internal)?\.aliyuncs\.com$`)
+)
+
+// normalizeEndpoint returns the endpoint with an explicit scheme and without trailing slashes. Endpoints
+// without a scheme use HTTPS, or plain HTTP if insecure is set, which rules out an explicit https://. The
+// SDK would otherwise default to plain HTTP and fail with obscure errors on endpoints with a path, so
+// endpoints other than a host with an optional port are rejected.
+func normalizeEndpoint(endpoint string, insecure bool) (string, error) {
+	e := strings.TrimSpace(endpoint)
+	scheme, host := "https", e
+	if insecure {
+		scheme = "http"
+	}
+	if i := strings.Index(e, "://"); i >= 0 {
+		scheme, host = strings.ToLower(e[:i]), e[i+3:]
+		switch {
+		case scheme != "http" && scheme != "https":
+			return "", errors.Errorf("invalid aliyun oss endpoint %s: unsupported scheme %s, expected http or https", endpoint, scheme)
+		case insecure && scheme == "https":
+			return "", errors.Errorf("invalid aliyun oss endpoint %s: insecure requires plain http", endpoint)
+		}
+	}
+	host = strings.TrimRight(host, "/")
+	u, err := url.Parse(scheme + "://" + host)
+	if err != nil {
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return "", errors.Wrapf(err, "invalid aliyun oss endpoint %s", endpoint)
+	}
+	switch {
+	case u.Host == "" || u.Hostname() == "":
+		return "", errors.Errorf("invalid aliyun oss endpoint %s: no host", endpoint)
+	case u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(host, "?") || strings.HasSuffix(host, "#"):
+		return "", errors.Errorf("invalid aliyun oss endpoint %s: must be a host with an optional port, e.g. oss-cn-hangzhou.aliyuncs.com", endpoint)
+	}
+	return scheme + "://" + u.Host, nil
+}
+
+// internalEndpoint returns the internal endpoint of the region of the given endpoint, which is either an
+// OSS endpoint like oss-cn-hangzhou.aliyuncs.com, optionally with a scheme, or a region ID like
+// cn-hangzhou or oss-cn-hangzhou. It reports false for any other endpoint, e.g. a custom domain or the
+// transfer acceleration endpoints, which have no internal form.
+func internalEndpoint(endpoint string) (string, bool) {
+	scheme, host := "", endpoint
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		scheme, host = endpoint[:i+3], endpoint[i+3:]
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), "/")
+
+	var region string
+	if m := publicEndpointPattern.FindStringSubmatch(host); m != nil {
+		region = m[1]
+	} else if scheme == "" && !strings.Contains(host, ".") {
+		region = strings.TrimPrefix(host, "oss-")
+	}
+	if !regionPattern.MatchString(region) || strings.HasPrefix(region, "accelerate") {
+		return endpoint, false
+	}
+	return scheme + "oss-" + region + "-internal.aliyuncs.com", true
+}
+
+// validateCNameEndpoint checks that the endpoint, optionally with a scheme, is a bare host usable as the
+// custom domain bound to the bucket: requests to custom domains address objects without the bucket name,
+// so neither OSS endpoints nor hosts prefixed with the bucket name work.
+func validateCNameEndpoint(endpoint, bucket string) error {
+	host := endpoint
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		if strings.Trim(host[i:], "/") != "" {
+			return errors.Errorf("aliyun oss cname endpoint %s must be a bare host without a path", endpoint)
+		}
+		host = host[:i]
+	}
+	host = strings.ToLower(host)
+	if strings.HasPrefix(host, strings.ToLower(bucket)+".") {
+		return errors.Errorf("aliyun oss cname endpoint %s must not be prefixed with the bucket name", endpoint)
+	}
+	if publicEndpointPattern.MatchString(host) {
+		return errors.Errorf("aliyun oss cname endpoint %s must be a custom domain rather than an OSS endpoint", endpoint)
+	}
+	return nil
+}
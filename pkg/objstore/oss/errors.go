@@ -0,0 +1,134 @@
+package oss
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// OSS error codes, see https://www.alibabacloud.com/help/doc-detail/32005.htm.
+const (
+	errCodeInvalidArgument       = "InvalidArgument"
+	errCodeRequestTimeTooSkewed  = "RequestTimeTooSkewed"
+	errCodeInvalidObjectState    = "InvalidObjectState"
+	errCodeNoSuchKey             = "NoSuchKey"
+	errCodeSymlinkTargetNotExist = "SymlinkTargetNotExist"
+	errCodeAccessDenied          = "AccessDenied"
+	errCodeInvalidAccessKeyID    = "InvalidAccessKeyId"
+	errCodeSignatureDoesNotMatch = "SignatureDoesNotMatch"
+	errCodeRestoreInProgress     = "RestoreAlreadyInProgress"
+	errCodeInvalidRange          = "InvalidRange"
+	errCodeNoSuchBucket          = "NoSuchBucket"
+	errCodePreconditionFailed    = "PreconditionFailed"
+)
+
+// serviceError returns the OSS service error err was caused by, if any.
+func serviceError(err error) (alioss.ServiceError, bool) {
+	switch aliErr := errors.Cause(err).(type) {
+	case alioss.ServiceError:
+		return aliErr, true
+	case *alioss.ServiceError:
+		return *aliErr, aliErr != nil
+	}
+	return alioss.ServiceError{}, false
+}
+
+// IsInvalidArgumentErr returns true if OSS rejected the request as malformed, e.g. because of an invalid
+// range or part number. Such errors are caused by the client and retrying the same request does not help.
+func IsInvalidArgumentErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && aliErr.Code == errCodeInvalidArgument
+}
+
+// IsRangeNotSatisfiableErr returns true if OSS refused a ranged read because the range starts past the end
+// of the object, which happens once the object got truncated. It is only returned with the standard range
+// behavior, otherwise OSS returns the whole object for such ranges.
+func IsRangeNotSatisfiableErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && (aliErr.Code == errCodeInvalidRange || aliErr.StatusCode == http.StatusRequestedRangeNotSatisfiable)
+}
+
+// IsNoSuchBucketErr returns true if OSS refused the request because the bucket does not exist.
+func IsNoSuchBucketErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && aliErr.Code == errCodeNoSuchBucket
+}
+
+// IsClockSkewErr returns true if OSS rejected the request because its signed time was too far off the
+// server time, which happens when the local clock drifted.
+func IsClockSkewErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && aliErr.Code == errCodeRequestTimeTooSkewed
+}
+
+// errReadOnly is the cause of errors returned by methods modifying a read-only bucket.
+var errReadOnly = errors.New("read-only")
+
+// IsReadOnlyErr returns true if the request was refused because the bucket is configured read_only.
+func IsReadOnlyErr(err error) bool {
+	return errors.Cause(err) == errReadOnly
+}
+
+// errObjectChanged is the cause of errors returned by UploadIfMatch when the object was modified.
+var errObjectChanged = errors.New("object changed")
+
+// IsObjectChangedErr returns true if UploadIfMatch refused to upload because the object was modified since
+// its ETag was read, either when checking the ETag or, on endpoints supporting conditional writes, when
+// writing the object.
+func IsObjectChangedErr(err error) bool {
+	return errors.Cause(err) == errObjectChanged || IsPreconditionFailedErr(err)
+}
+
+// IsPreconditionFailedErr returns true if the endpoint refused a conditional request because its If-Match
+// or If-None-Match precondition did not hold.
+func IsPreconditionFailedErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && (aliErr.Code == errCodePreconditionFailed || aliErr.StatusCode == http.StatusPreconditionFailed)
+}
+
+// IsInvalidObjectStateErr returns true if OSS refused to read the object in its current state. It is
+// returned briefly while an object transitions between storage classes, and for as long as an archived
+// object was not restored.
+func IsInvalidObjectStateErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && aliErr.Code == errCodeInvalidObjectState
+}
+
+// IsRestoreInProgressErr returns true if OSS refused to restore an object because it is being restored already.
+func IsRestoreInProgressErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && aliErr.Code == errCodeRestoreInProgress
+}
+
+// errChecksumMismatch is the cause of errors returned when the content read does not match its checksum.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// IsChecksumMismatchErr returns true if the content of an object read did not match the CRC64 checksum
+// computed by OSS, which means it got corrupted in transit.
+func IsChecksumMismatchErr(err error) bool {
+	return errors.Cause(err) == errChecksumMismatch
+}
+
+// withClockSkewHint annotates clock skew errors with how far the local clock is off and how to fix it.
+// Requests are signed with the local time by the SDK, so they cannot be retried with a corrected one.
+func withClockSkewHint(err error) error {
+	if !IsClockSkewErr(err) {
+		return err
+	}
+	aliErr, _ := serviceError(err)
+	var times struct {
+		RequestTime string `xml:"RequestTime"`
+		ServerTime  string `xml:"ServerTime"`
+	}
+	if xml.Unmarshal([]byte(aliErr.RawMessage), &times) == nil {
+		req, reqErr := time.Parse(time.RFC3339, times.RequestTime)
+		srv, srvErr := time.Parse(time.RFC3339, times.ServerTime)
+		if reqErr == nil && srvErr == nil {
+			return errors.Wrapf(err, "local clock is %s off the OSS server time, synchronize it e.g. with NTP", req.Sub(srv))
+		}
+	}
+	return errors.Wrap(err, "local clock is too far off the OSS server time, synchronize it e.g. with NTP")
+}
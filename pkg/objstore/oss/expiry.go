@@ -0,0 +1,48 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// ExpiryTagKey is the key of the object tag set by UploadWithExpiry to the number of days the object is to
+// be kept.
+const ExpiryTagKey = "expire-after-days"
+
+// UploadWithExpiry uploads the contents of the reader like Upload and tags the object to be deleted once
+// it is older than the given number of days.
+//
+// OSS has no per-object expiration: the Expires header only controls caching by clients. The object is
+// deleted by a bucket lifecycle rule matching the tag instead, which has to exist for every number of days
+// used, e.g. for 7 days:
+//
+//	<Rule>
+//	  <ID>expire-after-7-days</ID>
+//	  <Prefix></Prefix>
+//	  <Tag><Key>expire-after-days</Key><Value>7</Value></Tag>
+//	  <Status>Enabled</Status>
+//	  <Expiration><Days>7</Days></Expiration>
+//	</Rule>
+//
+// Without a matching rule the tag has no effect and the object is kept.
+func (b *Bucket) UploadWithExpiry(ctx context.Context, name string, r io.Reader, days int) error {
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+	if days < 1 {
+		return errors.Errorf("invalid expiry of %d days for oss object %s", days, name)
+	}
+
+	p := &uploadParams{tags: []alioss.Tag{{Key: ExpiryTagKey, Value: strconv.Itoa(days)}}}
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, p)
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	return err
+}
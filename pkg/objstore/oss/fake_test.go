@@ -0,0 +1,909 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+const fakeBucketName = "thanos"
+
+type fakeObject struct {
+	data    []byte
+	header  http.Header
+	modTime time.Time
+	// target is the key a symlink points to. Reads of symlinks return their target.
+	target string
+	// restoreDays is the number of days requested by the restore in progress, if any.
+	restoreDays int
+}
+
+type fakeUpload struct {
+	key       string
+	header    http.Header
+	parts     map[int][]byte
+	initiated time.Time
+}
+
+type fakeVersion struct {
+	key, id      string
+	deleteMarker bool
+	modTime      time.Time
+	data         []byte
+}
+
+type fakeRequest struct {
+	method string
+	key    string
+	query  string
+	header http.Header
+}
+
+// fakeOSS is an in-memory server speaking just enough of the OSS API for the SDK calls made by Bucket.
+type fakeOSS struct {
+	t   testing.TB
+	srv *httptest.Server
+
+	mtx     sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	// versions lists object versions, oldest first. Unless versioning is set they are only served by
+	// version listings.
+	versions []fakeVersion
+	// versioning makes writes and deletes of objects add versions, which can be read and deleted by ID.
+	versioning bool
+	// missingBucket makes every request fail as if the bucket did not exist, until it is created.
+	missingBucket bool
+	// conditionalWrites makes object writes honor If-Match and If-None-Match, like some OSS compatible
+	// endpoints do.
+	conditionalWrites bool
+	// omitNextMarker makes truncated listings leave out NextMarker, like some OSS compatible endpoints do.
+	omitNextMarker bool
+	requests       []fakeRequest
+	nextID         int
+
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeOSS(t testing.TB) *fakeOSS {
+	f := &fakeOSS{
+		t:       t,
+		objects: map[string]*fakeObject{},
+		uploads: map[string]*fakeUpload{},
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// newFakeOSSTLS returns a fake OSS served over HTTPS, supporting HTTP/2.
+func newFakeOSSTLS(t testing.TB) *fakeOSS {
+	f := &fakeOSS{
+		t:       t,
+		objects: map[string]*fakeObject{},
+		uploads: map[string]*fakeUpload{},
+	}
+	f.srv = httptest.NewUnstartedServer(http.HandlerFunc(f.serveHTTP))
+	f.srv.EnableHTTP2 = true
+	f.srv.StartTLS()
+	return f
+}
+
+// trust makes the transport accept the certificate of the fake OSS.
+func (f *fakeOSS) trust(t *http.Transport) {
+	pool := x509.NewCertPool()
+	pool.AddCert(f.srv.Certificate())
+	t.TLSClientConfig = &tls.Config{RootCAs: pool}
+}
+
+func (f *fakeOSS) Close() { f.srv.Close() }
+
+func (f *fakeOSS) config() Config {
+	return Config{
+		Endpoint:        f.srv.URL,
+		Bucket:          fakeBucketName,
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+	}
+}
+
+// newBucket creates a Bucket talking to the fake server. Extra YAML may be given to set additional config fields.
+func (f *fakeOSS) newBucket(extra string, opts ...Option) *Bucket {
+	conf, err := yaml.Marshal(f.config())
+	testutil.Ok(f.t, err)
+
+	b, err := NewBucket(log.NewNopLogger(), append(conf, []byte(extra)...), "thanos-test", opts...)
+	testutil.Ok(f.t, err)
+	return b
+}
+
+// setIntercept installs a handler consulted before the default handling. Returning true means the request was handled.
+func (f *fakeOSS) setIntercept(fn func(w http.ResponseWriter, r *http.Request) bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.intercept = fn
+}
+
+func (f *fakeOSS) put(key string, data []byte) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.objects[key] = &fakeObject{data: data, header: http.Header{}, modTime: time.Now()}
+}
+
+func (f *fakeOSS) object(key string) (*fakeObject, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	o, ok := f.objects[key]
+	return o, ok
+}
+
+func (f *fakeOSS) countRequests(method string, hasQuery string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	n := 0
+	for _, r := range f.requests {
+		if r.method != method {
+			continue
+		}
+		if hasQuery != "" && !strings.Contains(r.query, hasQuery) {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func (f *fakeOSS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	split := strings.SplitN(path, "/", 2)
+	key := ""
+	if len(split) == 2 {
+		key = split[1]
+	}
+
+	f.mtx.Lock()
+	f.requests = append(f.requests, fakeRequest{method: r.Method, key: key, query: r.URL.RawQuery, header: r.Header.Clone()})
+	intercept := f.intercept
+	f.mtx.Unlock()
+
+	if intercept != nil && intercept(w, r) {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	q := r.URL.Query()
+	if f.missingBucket {
+		if key == "" && r.Method == http.MethodPut && len(q) == 0 {
+			f.missingBucket = false
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		writeFakeError(w, http.StatusNotFound, "NoSuchBucket", "bucket does not exist")
+		return
+	}
+	if key == "" {
+		switch {
+		case r.Method == http.MethodGet && hasParam(q, "bucketInfo"):
+			writeFakeXML(w, alioss.GetBucketInfoResult{BucketInfo: alioss.BucketInfo{Name: fakeBucketName}})
+		case r.Method == http.MethodGet && hasParam(q, "versions"):
+			f.listVersions(w, q)
+		case r.Method == http.MethodGet && hasParam(q, "uploads"):
+			f.listUploads(w, q)
+		case r.Method == http.MethodGet && hasParam(q, "stat"):
+			stat := alioss.BucketStat{MultipartUploadCount: int64(len(f.uploads))}
+			for _, o := range f.objects {
+				stat.Storage += int64(len(o.data))
+				stat.ObjectCount++
+			}
+			writeFakeXML(w, stat)
+		case r.Method == http.MethodPost && hasParam(q, "delete"):
+			f.deleteObjects(w, body)
+		case r.Method == http.MethodGet:
+			f.listObjects(w, q)
+		default:
+			writeFakeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" on bucket")
+		}
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && hasParam(q, "uploads"):
+		f.initiateUpload(w, r, key)
+	case r.Method == http.MethodGet && hasParam(q, "tagging"):
+		f.getTagging(w, key)
+	case r.Method == http.MethodPut && hasParam(q, "tagging"):
+		f.putTagging(w, key, body)
+	case r.Method == http.MethodPut && hasParam(q, "acl"):
+		o, ok := f.objects[key]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		o.header.Set(alioss.HTTPHeaderOssObjectACL, r.Header.Get(alioss.HTTPHeaderOssObjectACL))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && q.Get("uploadId") != "":
+		f.listParts(w, q)
+	case r.Method == http.MethodPut && q.Get("uploadId") != "":
+		f.uploadPart(w, r, q, body)
+	case r.Method == http.MethodPost && q.Get("uploadId") != "":
+		f.completeUpload(w, r, key, q, body)
+	case r.Method == http.MethodDelete && q.Get("uploadId") != "":
+		if _, ok := f.uploads[q.Get("uploadId")]; !ok {
+			writeFakeError(w, http.StatusNotFound, "NoSuchUpload", "upload does not exist")
+			return
+		}
+		delete(f.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && hasParam(q, "restore"):
+		f.restoreObject(w, key, body)
+	case r.Method == http.MethodPut && hasParam(q, "symlink"):
+		target, err := url.QueryUnescape(r.Header.Get("X-Oss-Symlink-Target"))
+		if err != nil || target == "" {
+			writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid symlink target")
+			return
+		}
+		f.objects[key] = &fakeObject{header: http.Header{}, modTime: time.Now(), target: target}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && r.Header.Get("X-Oss-Copy-Source") != "":
+		f.copyObject(w, r, key)
+	case r.Method == http.MethodPut:
+		if !f.writeAllowed(w, r, key) {
+			return
+		}
+		f.objects[key] = &fakeObject{data: body, header: objectHeaders(r.Header), modTime: time.Now()}
+		f.addVersion(w, fakeVersion{key: key, data: body})
+		w.Header().Set("ETag", etag(body))
+		f.respond(w, r, key, nil)
+	case r.Method == http.MethodGet && q.Get("versionId") != "":
+		f.getVersion(w, key, q.Get("versionId"))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		f.getObject(w, r, key)
+	case r.Method == http.MethodDelete && q.Get("versionId") != "":
+		f.deleteVersion(w, key, q.Get("versionId"))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		f.addVersion(w, fakeVersion{key: key, deleteMarker: true})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" on object")
+	}
+}
+
+// writeAllowed checks the preconditions of a write with conditional writes enabled, failing it with 412
+// Precondition Failed unless they hold.
+func (f *fakeOSS) writeAllowed(w http.ResponseWriter, r *http.Request, key string) bool {
+	if !f.conditionalWrites {
+		return true
+	}
+	o, exists := f.objects[key]
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	if (ifMatch != "" && (!exists || ifMatch != etag(o.data))) || (ifNoneMatch == "*" && exists) {
+		writeFakeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+		return false
+	}
+	return true
+}
+
+func (f *fakeOSS) deleteObjects(w http.ResponseWriter, body []byte) {
+	var req struct {
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Objects) == 0 || len(req.Objects) > maxDeleteObjects {
+		writeFakeError(w, http.StatusBadRequest, "MalformedXML", "invalid delete request")
+		return
+	}
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	res := struct {
+		XMLName xml.Name  `xml:"DeleteResult"`
+		Deleted []deleted `xml:"Deleted"`
+	}{}
+	for _, o := range req.Objects {
+		delete(f.objects, o.Key)
+		// Keys are returned URL encoded, as requested by the SDK.
+		res.Deleted = append(res.Deleted, deleted{Key: url.QueryEscape(o.Key)})
+	}
+	writeFakeXML(w, res)
+}
+
+func (f *fakeOSS) restoreObject(w http.ResponseWriter, key string, body []byte) {
+	o, ok := f.objects[key]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "object does not exist")
+		return
+	}
+	if o.restoreDays != 0 {
+		writeFakeError(w, http.StatusConflict, "RestoreAlreadyInProgress", "restore in progress")
+		return
+	}
+	var req struct {
+		Days int `xml:"Days"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil || req.Days < 1 {
+		writeFakeError(w, http.StatusBadRequest, "MalformedXML", "invalid restore request")
+		return
+	}
+	o.restoreDays = req.Days
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fakeOSS) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	o, ok := f.objects[key]
+	if ok && o.target != "" {
+		o, ok = f.objects[o.target]
+	}
+	if !ok {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	for k, v := range o.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", etag(o.data))
+	w.Header().Set("Last-Modified", o.modTime.UTC().Format(http.TimeFormat))
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if inm == etag(o.data) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !o.modTime.Truncate(time.Second).After(ims) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	for k, v := range r.URL.Query() {
+		if h := strings.TrimPrefix(k, "response-"); h != k {
+			w.Header().Set(h, v[0])
+		}
+	}
+
+	data, status := o.data, http.StatusOK
+	// Like OSS without the standard range behavior, an invalid range is ignored and the whole object is returned.
+	if start, end, ok := parseFakeRange(r.Header.Get("Range"), int64(len(o.data))); ok {
+		data, status = o.data[start:end+1], http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(o.data)))
+	} else {
+		w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(crc64.Checksum(o.data, crc64.MakeTable(crc64.ECMA)), 10))
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+// addVersion records the version written with versioning enabled and returns its ID in the response.
+func (f *fakeOSS) addVersion(w http.ResponseWriter, v fakeVersion) {
+	if !f.versioning {
+		return
+	}
+	f.nextID++
+	v.id, v.modTime = fmt.Sprintf("version-%d", f.nextID), time.Now()
+	f.versions = append(f.versions, v)
+	w.Header().Set("X-Oss-Version-Id", v.id)
+}
+
+func (f *fakeOSS) getVersion(w http.ResponseWriter, key, id string) {
+	for _, v := range f.versions {
+		if v.key != key || v.id != id || v.deleteMarker {
+			continue
+		}
+		w.Header().Set("ETag", etag(v.data))
+		w.Header().Set("Last-Modified", v.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("X-Oss-Version-Id", id)
+		w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(crc64.Checksum(v.data, crc64.MakeTable(crc64.ECMA)), 10))
+		w.Header().Set("Content-Length", strconv.Itoa(len(v.data)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(v.data)
+		return
+	}
+	writeFakeError(w, http.StatusNotFound, "NoSuchVersion", "The specified version does not exist.")
+}
+
+// deleteVersion removes the version, making the previous version of the key the current object if it was
+// the latest one.
+func (f *fakeOSS) deleteVersion(w http.ResponseWriter, key, id string) {
+	var (
+		latest  *fakeVersion
+		removed bool
+	)
+	for i := 0; i < len(f.versions); i++ {
+		v := f.versions[i]
+		if v.key != key {
+			continue
+		}
+		if v.id == id {
+			f.versions = append(f.versions[:i], f.versions[i+1:]...)
+			i--
+			removed = true
+			continue
+		}
+		latest = &v
+	}
+	if !removed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	delete(f.objects, key)
+	if latest != nil && !latest.deleteMarker {
+		f.objects[key] = &fakeObject{data: latest.data, header: http.Header{}, modTime: latest.modTime}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeOSS) getTagging(w http.ResponseWriter, key string) {
+	o, ok := f.objects[key]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	tags, err := url.ParseQuery(o.header.Get("X-Oss-Tagging"))
+	if err != nil {
+		writeFakeError(w, http.StatusInternalServerError, "InternalError", "invalid stored tags")
+		return
+	}
+	var res alioss.Tagging
+	for k := range tags {
+		res.Tags = append(res.Tags, alioss.Tag{Key: k, Value: tags.Get(k)})
+	}
+	sort.Slice(res.Tags, func(i, j int) bool { return res.Tags[i].Key < res.Tags[j].Key })
+	writeFakeXML(w, res)
+}
+
+func (f *fakeOSS) putTagging(w http.ResponseWriter, key string, body []byte) {
+	o, ok := f.objects[key]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	var req alioss.Tagging
+	if err := xml.Unmarshal(body, &req); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "MalformedXML", "invalid tagging")
+		return
+	}
+	tags := url.Values{}
+	for _, t := range req.Tags {
+		tags.Set(t.Key, t.Value)
+	}
+	o.header.Set("X-Oss-Tagging", tags.Encode())
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *fakeOSS) copyObject(w http.ResponseWriter, r *http.Request, key string) {
+	src, err := url.QueryUnescape(strings.TrimPrefix(r.Header.Get("X-Oss-Copy-Source"), "/"+fakeBucketName+"/"))
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source")
+		return
+	}
+	o, ok := f.objects[src]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	header := o.header
+	if r.Header.Get("X-Oss-Metadata-Directive") == "REPLACE" {
+		header = objectHeaders(r.Header)
+	}
+	f.objects[key] = &fakeObject{data: o.data, header: header, modTime: time.Now()}
+	writeFakeXML(w, alioss.CopyObjectResult{LastModified: time.Now(), ETag: etag(o.data)})
+}
+
+func (f *fakeOSS) listObjects(w http.ResponseWriter, q map[string][]string) {
+	prefix, delim, marker := param(q, "prefix"), param(q, "delimiter"), param(q, "marker")
+	maxKeys := 100
+	if v := param(q, "max-keys"); v != "" {
+		maxKeys, _ = strconv.Atoi(v)
+	}
+
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := alioss.ListObjectsResult{Prefix: prefix, Marker: marker, MaxKeys: maxKeys, Delimiter: delim}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= marker {
+			continue
+		}
+		// A marker pointing at a common prefix skips everything below it.
+		if delim != "" && strings.HasSuffix(marker, delim) && strings.HasPrefix(k, marker) {
+			continue
+		}
+		entry, common := k, false
+		if delim != "" {
+			if i := strings.Index(k[len(prefix):], delim); i >= 0 {
+				entry, common = k[:len(prefix)+i+len(delim)], true
+			}
+		}
+		if seen[entry] {
+			continue
+		}
+		if len(res.Objects)+len(res.CommonPrefixes) == maxKeys {
+			res.IsTruncated = true
+			break
+		}
+		seen[entry] = true
+		res.NextMarker = entry
+		if common {
+			res.CommonPrefixes = append(res.CommonPrefixes, entry)
+			continue
+		}
+		o := f.objects[k]
+		res.Objects = append(res.Objects, alioss.ObjectProperties{
+			Key:          k,
+			Size:         int64(len(o.data)),
+			ETag:         etag(o.data),
+			LastModified: o.modTime,
+			StorageClass: "Standard",
+			Type:         "Normal",
+			Owner:        alioss.Owner{ID: "owner-id", DisplayName: "owner"},
+		})
+	}
+	if !res.IsTruncated || f.omitNextMarker {
+		res.NextMarker = ""
+	}
+	enc := listingEncoder(q)
+	res.Prefix, res.Marker, res.Delimiter, res.NextMarker = enc(res.Prefix), enc(res.Marker), enc(res.Delimiter), enc(res.NextMarker)
+	for i := range res.Objects {
+		res.Objects[i].Key = enc(res.Objects[i].Key)
+	}
+	for i := range res.CommonPrefixes {
+		res.CommonPrefixes[i] = enc(res.CommonPrefixes[i])
+	}
+	writeFakeXML(w, res)
+}
+
+// listingEncoder returns the function encoding the keys, prefixes and markers of a listing as requested
+// by its encoding-type, which the SDK always sets to url to decode them.
+func listingEncoder(q map[string][]string) func(string) string {
+	if param(q, "encoding-type") != "url" {
+		return func(s string) string { return s }
+	}
+	return url.QueryEscape
+}
+
+func (f *fakeOSS) listVersions(w http.ResponseWriter, q map[string][]string) {
+	prefix, keyMarker, idMarker := param(q, "prefix"), param(q, "key-marker"), param(q, "version-id-marker")
+	maxKeys := 100
+	if v := param(q, "max-keys"); v != "" {
+		maxKeys, _ = strconv.Atoi(v)
+	}
+
+	// Versions are listed by key, newest first.
+	versions := make([]fakeVersion, 0, len(f.versions))
+	latest := map[string]string{}
+	for _, v := range f.versions {
+		versions = append([]fakeVersion{v}, versions...)
+		latest[v.key] = v.id
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].key < versions[j].key })
+
+	res := alioss.ListObjectVersionsResult{Name: fakeBucketName, Prefix: prefix, KeyMarker: keyMarker, VersionIdMarker: idMarker, MaxKeys: maxKeys}
+	skipping, n := keyMarker != "", 0
+	for _, v := range versions {
+		if skipping {
+			if v.key == keyMarker && v.id == idMarker {
+				skipping = false
+			}
+			continue
+		}
+		if !strings.HasPrefix(v.key, prefix) {
+			continue
+		}
+		if n == maxKeys {
+			res.IsTruncated = true
+			break
+		}
+		n++
+		res.NextKeyMarker, res.NextVersionIdMarker = v.key, v.id
+		if v.deleteMarker {
+			res.ObjectDeleteMarkers = append(res.ObjectDeleteMarkers, alioss.ObjectDeleteMarkerProperties{
+				Key: v.key, VersionId: v.id, IsLatest: latest[v.key] == v.id, LastModified: v.modTime,
+			})
+			continue
+		}
+		res.ObjectVersions = append(res.ObjectVersions, alioss.ObjectVersionProperties{
+			Key: v.key, VersionId: v.id, IsLatest: latest[v.key] == v.id, LastModified: v.modTime,
+		})
+	}
+	if !res.IsTruncated {
+		res.NextKeyMarker, res.NextVersionIdMarker = "", ""
+	}
+	enc := listingEncoder(q)
+	res.Prefix, res.KeyMarker, res.NextKeyMarker = enc(res.Prefix), enc(res.KeyMarker), enc(res.NextKeyMarker)
+	for i := range res.ObjectVersions {
+		res.ObjectVersions[i].Key = enc(res.ObjectVersions[i].Key)
+	}
+	for i := range res.ObjectDeleteMarkers {
+		res.ObjectDeleteMarkers[i].Key = enc(res.ObjectDeleteMarkers[i].Key)
+	}
+	writeFakeXML(w, res)
+}
+
+func (f *fakeOSS) initiateUpload(w http.ResponseWriter, r *http.Request, key string) {
+	f.nextID++
+	id := fmt.Sprintf("upload-%d", f.nextID)
+	f.uploads[id] = &fakeUpload{key: key, header: objectHeaders(r.Header), parts: map[int][]byte{}, initiated: time.Now()}
+	writeFakeXML(w, alioss.InitiateMultipartUploadResult{Bucket: fakeBucketName, Key: key, UploadID: id})
+}
+
+func (f *fakeOSS) uploadPart(w http.ResponseWriter, r *http.Request, q map[string][]string, body []byte) {
+	u, ok := f.uploads[param(q, "uploadId")]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchUpload", "upload does not exist")
+		return
+	}
+	n, err := strconv.Atoi(param(q, "partNumber"))
+	if err != nil || n < 1 || n > MaxParts {
+		writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+	if r.Header.Get("X-Oss-Copy-Source") == "" {
+		u.parts[n] = body
+		w.Header().Set("ETag", etag(body))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	src, err := url.QueryUnescape(strings.TrimPrefix(r.Header.Get("X-Oss-Copy-Source"), "/"+fakeBucketName+"/"))
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source")
+		return
+	}
+	o, ok := f.objects[src]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	start, end, ok := parseFakeRange(r.Header.Get("X-Oss-Copy-Source-Range"), int64(len(o.data)))
+	if !ok {
+		writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source range")
+		return
+	}
+	u.parts[n] = o.data[start : end+1]
+	w.Header().Set("ETag", etag(u.parts[n]))
+	writeFakeXML(w, alioss.UploadPartCopyResult{ETag: etag(u.parts[n])})
+}
+
+func (f *fakeOSS) completeUpload(w http.ResponseWriter, r *http.Request, key string, q map[string][]string, body []byte) {
+	id := param(q, "uploadId")
+	u, ok := f.uploads[id]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchUpload", "upload does not exist")
+		return
+	}
+	if !f.writeAllowed(w, r, key) {
+		return
+	}
+	var req struct {
+		Parts []alioss.UploadPart `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+		writeFakeError(w, http.StatusBadRequest, "MalformedXML", "invalid part list")
+		return
+	}
+	var data []byte
+	for i, p := range req.Parts {
+		part, ok := u.parts[p.PartNumber]
+		if !ok || etag(part) != p.ETag || (i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber) {
+			writeFakeError(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("invalid part %d", p.PartNumber))
+			return
+		}
+		data = append(data, part...)
+	}
+	delete(f.uploads, id)
+	f.objects[key] = &fakeObject{data: data, header: u.header, modTime: time.Now()}
+	f.respond(w, r, key, alioss.CompleteMultipartUploadResult{Bucket: fakeBucketName, Key: key, ETag: etag(data)})
+}
+
+// respond finishes a request writing an object. If the request carries an upload callback, the callback
+// server is invoked and its response relayed instead of the regular result.
+func (f *fakeOSS) respond(w http.ResponseWriter, r *http.Request, key string, result interface{}) {
+	if h := r.Header.Get("X-Oss-Callback"); h != "" {
+		raw, err := base64.StdEncoding.DecodeString(h)
+		if err != nil {
+			writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "callback is not base64")
+			return
+		}
+		var cb Callback
+		if err := json.Unmarshal(raw, &cb); err != nil {
+			writeFakeError(w, http.StatusBadRequest, "InvalidArgument", "callback is not valid json")
+			return
+		}
+		body := strings.Replace(cb.Body, "${object}", key, -1)
+		body = strings.Replace(body, "${size}", strconv.Itoa(len(f.objects[key].data)), -1)
+		resp, err := http.Post(cb.URL, cb.BodyType, strings.NewReader(body))
+		if err != nil {
+			writeFakeError(w, 203, "CallbackFailed", err.Error())
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		_, _ = w.Write(b)
+		return
+	}
+	if result == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	writeFakeXML(w, result)
+}
+
+func (f *fakeOSS) listUploads(w http.ResponseWriter, q map[string][]string) {
+	res := alioss.ListMultipartUploadResult{Bucket: fakeBucketName}
+	for id, u := range f.uploads {
+		if !strings.HasPrefix(u.key, param(q, "prefix")) {
+			continue
+		}
+		res.Uploads = append(res.Uploads, alioss.UncompletedUpload{Key: listingEncoder(q)(u.key), UploadID: id, Initiated: u.initiated})
+	}
+	sort.Slice(res.Uploads, func(i, j int) bool { return res.Uploads[i].UploadID < res.Uploads[j].UploadID })
+	writeFakeXML(w, res)
+}
+
+func (f *fakeOSS) listParts(w http.ResponseWriter, q map[string][]string) {
+	u, ok := f.uploads[param(q, "uploadId")]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "NoSuchUpload", "upload does not exist")
+		return
+	}
+	res := alioss.ListUploadedPartsResult{Bucket: fakeBucketName, Key: u.key, UploadID: param(q, "uploadId")}
+	for n, data := range u.parts {
+		res.UploadedParts = append(res.UploadedParts, alioss.UploadedPart{PartNumber: n, ETag: etag(data), Size: len(data)})
+	}
+	sort.Slice(res.UploadedParts, func(i, j int) bool { return res.UploadedParts[i].PartNumber < res.UploadedParts[j].PartNumber })
+	writeFakeXML(w, res)
+}
+
+// objectHeaders picks the request headers OSS stores along with an object.
+func objectHeaders(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		if k == "Content-Type" || (strings.HasPrefix(k, "X-Oss-") && k != "X-Oss-Date" &&
+			k != "X-Oss-Copy-Source" && k != "X-Oss-Metadata-Directive") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func parseFakeRange(h string, size int64) (int64, int64, bool) {
+	if !strings.HasPrefix(h, "bytes=") {
+		return 0, 0, false
+	}
+	split := strings.SplitN(strings.TrimPrefix(h, "bytes="), "-", 2)
+	if len(split) != 2 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(split[0], 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if split[1] != "" {
+		if end, err = strconv.ParseInt(split[1], 10, 64); err != nil || end < start || end >= size {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
+
+func hasParam(q map[string][]string, name string) bool {
+	_, ok := q[name]
+	return ok
+}
+
+func param(q map[string][]string, name string) string {
+	if v := q[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`
+}
+
+func writeFakeXML(w http.ResponseWriter, v interface{}) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write(b)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	var buf bytes.Buffer
+	_ = xml.NewEncoder(&buf).Encode(struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string   `xml:"Code"`
+		Message   string   `xml:"Message"`
+		RequestID string   `xml:"RequestId"`
+	}{Code: code, Message: msg, RequestID: "fake"})
+	_, _ = w.Write(buf.Bytes())
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// endpoint returns a transport sending requests to the given server instead.
+func endpoint(srv *httptest.Server) http.RoundTripper {
+	target, _ := url.Parse(srv.URL)
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Host = target.Host
+		r.Host = target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+}
+
+// withTimeout returns a transport failing requests which take longer than d to get a response.
+func withTimeout(rt http.RoundTripper, d time.Duration) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		resp, err := rt.RoundTrip(r.WithContext(ctx))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	})
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
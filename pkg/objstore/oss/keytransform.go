@@ -0,0 +1,28 @@
+package oss
+
+// KeyTransform maps the logical object keys used by callers to the physical keys stored in OSS, e.g. to
+// serve a key layout inherited from another provider. A transform must keep directories intact: the
+// physical key of dir+name has to start with the physical key of dir, so that listings can be mapped.
+type KeyTransform interface {
+	// Physical returns the OSS key of the object with the given logical key.
+	Physical(key string) string
+	// Logical is the inverse of Physical. It returns false for OSS keys that have no logical key, which
+	// are then hidden from listings.
+	Logical(key string) (string, bool)
+}
+
+// physical returns the OSS key of the object with the given logical key.
+func (b *Bucket) physical(name string) string {
+	if b.keys == nil {
+		return name
+	}
+	return b.keys.Physical(name)
+}
+
+// logical returns the logical key of the object stored at the given OSS key.
+func (b *Bucket) logical(key string) (string, bool) {
+	if b.keys == nil {
+		return key, true
+	}
+	return b.keys.Logical(key)
+}
@@ -0,0 +1,72 @@
+package oss
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Limiter caps the number of concurrent requests to OSS. A single Limiter may be shared by all the buckets
+// of a process, e.g. one per tenant, to bound their combined load:
+//
+//	limiter := oss.NewLimiter(64)
+//	a, err := oss.NewBucket(logger, confA, "tenant-a", oss.WithLimiter(limiter))
+//	...
+//	b, err := oss.NewBucket(logger, confB, "tenant-b", oss.WithLimiter(limiter))
+//
+// A request holds its slot until the response body is closed, so readers returned by Get and GetRange
+// count against the limit until they are closed.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing up to n concurrent requests.
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// WithLimiter makes the Bucket wait for a slot of the given Limiter before sending each request.
+func WithLimiter(l *Limiter) Option {
+	return optionFunc(func(b *Bucket) {
+		b.limiter = l
+	})
+}
+
+func (l *Limiter) release() { <-l.slots }
+
+// limitedTransport sends requests through next once a slot of the limiter is free.
+type limitedTransport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+func (t limitedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	select {
+	case t.limiter.slots <- struct{}{}:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		t.limiter.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: t.limiter.release}
+	return resp, nil
+}
+
+// releasingBody releases the slot of its request once closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
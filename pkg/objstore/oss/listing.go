@@ -0,0 +1,53 @@
+package oss
+
+import (
+	"context"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// ObjectInfo holds the metadata of an object returned by listings.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	// StorageClass is one of Standard, IA, Archive or ColdArchive.
+	StorageClass string
+	// Type is Normal, Multipart, Appendable or Symlink, depending on how the object was written.
+	Type             string
+	OwnerID          string
+	OwnerDisplayName string
+	// IsDir is set for directory entries, which only have Key set otherwise.
+	IsDir bool
+}
+
+// IterObjects calls f for each entry in the given directory like Iter, passing the metadata returned
+// by the listing along with the name, so no request per object is needed. Like Iter, it lists the
+// subdirectories too with objstore.WithRecursiveIter.
+//
+// The SDK only offers the ListObjects (V1) API, which already returns the owner and metadata of every
+// object; ListObjectsV2 would only add them on request.
+func (b *Bucket) IterObjects(ctx context.Context, dir string, f func(ObjectInfo) error, options ...objstore.IterOption) error {
+	b = b.operation("iter")
+	start := time.Now()
+	params := objstore.ApplyIterOptions(options...)
+	err := b.iter(ctx, dir, params, func(o alioss.ObjectProperties) error {
+		return f(ObjectInfo{
+			Key:              o.Key,
+			Size:             o.Size,
+			ETag:             o.ETag,
+			LastModified:     o.LastModified,
+			StorageClass:     o.StorageClass,
+			Type:             o.Type,
+			OwnerID:          o.Owner.ID,
+			OwnerDisplayName: o.Owner.DisplayName,
+		})
+	}, func(name string) error {
+		return f(ObjectInfo{Key: name, IsDir: true})
+	})
+	b.observe("iter", dir, start, err, "recursive", params.Recursive)
+	return err
+}
@@ -0,0 +1,85 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// listTimeoutTransport fails listing requests taking longer than the timeout in total, body included,
+// leaving other requests alone. The SDK cannot set deadlines on individual requests, so listings are
+// recognized as the GET requests addressing the bucket rather than an object.
+type listTimeoutTransport struct {
+	bucket  string
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t listTimeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet || !t.bucketRequest(r) {
+		return t.next.RoundTrip(r)
+	}
+	parent := r.Context()
+	ctx, cancel := context.WithTimeout(parent, t.timeout)
+	resp, err := t.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, t.mapErr(parent, ctx, err)
+	}
+	resp.Body = &listTimeoutBody{ReadCloser: resp.Body, t: t, parent: parent, ctx: ctx, cancel: cancel}
+	return resp, nil
+}
+
+// bucketRequest reports whether the request addresses the bucket itself, with either virtual hosted or
+// path style URLs.
+func (t listTimeoutTransport) bucketRequest(r *http.Request) bool {
+	path := strings.Trim(r.URL.Path, "/")
+	if strings.HasPrefix(r.URL.Host, t.bucket+".") {
+		return path == ""
+	}
+	return path == t.bucket
+}
+
+// mapErr returns a listTimeoutError if err was caused by the timeout rather than the caller.
+func (t listTimeoutTransport) mapErr(parent, ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		return listTimeoutError{timeout: t.timeout}
+	}
+	return err
+}
+
+type listTimeoutBody struct {
+	io.ReadCloser
+	t           listTimeoutTransport
+	parent, ctx context.Context
+	cancel      context.CancelFunc
+}
+
+func (b *listTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.t.mapErr(b.parent, b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *listTimeoutBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// listTimeoutError is returned for listing requests exceeding the list timeout. It is a network timeout
+// error, which the retry logic considers transient.
+type listTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e listTimeoutError) Error() string {
+	return fmt.Sprintf("listing page not received within list_timeout %s", e.timeout)
+}
+
+func (listTimeoutError) Timeout() bool   { return true }
+func (listTimeoutError) Temporary() bool { return true }
@@ -0,0 +1,124 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// MaxUserMetadataSize is the maximum total size OSS accepts of the user metadata of an object, counting
+// the keys and values.
+const MaxUserMetadataSize = 8 * 1024
+
+// validMetaKeyRune reports whether the character is allowed in user metadata keys: lower case letters,
+// digits and hyphens, as OSS lower cases keys and proxies may drop headers with other characters.
+func validMetaKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-'
+}
+
+// userMetadata validates the user metadata and returns it with lower cased keys.
+func userMetadata(meta map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(meta))
+	size := 0
+	for k, v := range meta {
+		key := strings.ToLower(k)
+		if key == "" {
+			return nil, errors.New("invalid metadata: empty key")
+		}
+		for _, r := range key {
+			if !validMetaKeyRune(r) {
+				return nil, errors.Errorf("invalid metadata key %q: character %q is not allowed, only letters, digits and - are", k, r)
+			}
+		}
+		if key == sha256MetaKey {
+			return nil, errors.Errorf("invalid metadata key %q: reserved for sha256_metadata", k)
+		}
+		if _, ok := out[key]; ok {
+			return nil, errors.Errorf("invalid metadata key %q: set more than once", k)
+		}
+		for _, r := range v {
+			if r < ' ' || r > '~' {
+				return nil, errors.Errorf("invalid metadata value %q of key %q: only printable ASCII characters are allowed", v, k)
+			}
+		}
+		out[key] = v
+		size += len(key) + len(v)
+	}
+	if size > MaxUserMetadataSize {
+		return nil, errors.Errorf("metadata of %d bytes exceeds the %d bytes allowed by OSS", size, MaxUserMetadataSize)
+	}
+	return out, nil
+}
+
+// metaOptions returns the options sending the user metadata, ordered by key.
+func metaOptions(meta map[string]string) []alioss.Option {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	opts := make([]alioss.Option, 0, len(keys))
+	for _, k := range keys {
+		opts = append(opts, alioss.Meta(k, meta[k]))
+	}
+	return opts
+}
+
+// UploadWithMeta is Upload storing the given user metadata with the object, sent as x-oss-meta-* headers.
+// Keys are case insensitive and stored lower cased.
+func (b *Bucket) UploadWithMeta(ctx context.Context, name string, r io.Reader, meta map[string]string) error {
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+	m, err := userMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, &uploadParams{meta: m})
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	return err
+}
+
+// GetObjectUserMeta returns the user metadata of the object by lower cased key, including the metadata
+// set by the Bucket itself, like the SHA256 of SHA256Metadata.
+func (b *Bucket) GetObjectUserMeta(ctx context.Context, name string) (map[string]string, error) {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// GetObjectMeta only returns the size, ETag and modification time, the user metadata needs a HEAD.
+	var header http.Header
+	err = b.retry(ctx, func() (err error) {
+		header, err = b.bucket.GetObjectDetailedMeta(name)
+		return err
+	})
+	if err != nil {
+		return nil, withClockSkewHint(errors.Wrapf(err, "get meta of oss object %s", name))
+	}
+	meta := map[string]string{}
+	for k, v := range header {
+		if len(v) > 0 && len(k) > len(alioss.HTTPHeaderOssMetaPrefix) && strings.EqualFold(k[:len(alioss.HTTPHeaderOssMetaPrefix)], alioss.HTTPHeaderOssMetaPrefix) {
+			meta[strings.ToLower(k[len(alioss.HTTPHeaderOssMetaPrefix):])] = v[0]
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,136 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Operations instrumented by the metrics of a Bucket.
+var metricOps = []string{"upload", "get", "get_range", "delete", "iter", "exists", "attributes"}
+
+// metrics instruments the operations of a Bucket. Unlike objstore.BucketWithMetrics, which only sees the
+// objstore.Bucket interface, uploads of all Upload* methods are counted as upload.
+type metrics struct {
+	ops         *prometheus.CounterVec
+	opsFailures *prometheus.CounterVec
+	opsDuration *prometheus.HistogramVec
+	// uploadedBytes counts the bytes sent by upload requests, including those sent again by retries.
+	uploadedBytes prometheus.Counter
+	// downloadedBytes counts the bytes read by callers from the readers returned by Get and GetRange.
+	downloadedBytes prometheus.Counter
+}
+
+func newMetrics(bucket string) *metrics {
+	labels := prometheus.Labels{"bucket": bucket}
+	m := &metrics{
+		ops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_oss_operations_total",
+			Help:        "Total number of operations against the aliyun oss bucket.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		opsFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_oss_operation_failures_total",
+			Help:        "Total number of operations against the aliyun oss bucket that failed.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		opsDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "thanos_objstore_oss_operation_duration_seconds",
+			Help:        "Duration of operations against the aliyun oss bucket. Reads are timed until the reader is returned.",
+			ConstLabels: labels,
+			Buckets:     []float64{0.005, 0.01, 0.02, 0.04, 0.08, 0.15, 0.3, 0.6, 1, 1.5, 2.5, 5, 10, 20, 30},
+		}, []string{"operation"}),
+		uploadedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "thanos_objstore_oss_uploaded_bytes_total",
+			Help:        "Total number of bytes sent to the aliyun oss bucket by uploads, including retries.",
+			ConstLabels: labels,
+		}),
+		downloadedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "thanos_objstore_oss_downloaded_bytes_total",
+			Help:        "Total number of bytes of objects read from the aliyun oss bucket.",
+			ConstLabels: labels,
+		}),
+	}
+	for _, op := range metricOps {
+		m.ops.WithLabelValues(op)
+		m.opsFailures.WithLabelValues(op)
+	}
+	return m
+}
+
+// register registers the collectors with reg, all of them or none.
+func (m *metrics) register(reg prometheus.Registerer) error {
+	cs := []prometheus.Collector{m.ops, m.opsFailures, m.opsDuration, m.uploadedBytes, m.downloadedBytes}
+	for i, c := range cs {
+		if err := reg.Register(c); err != nil {
+			for _, registered := range cs[:i] {
+				reg.Unregister(registered)
+			}
+			return errors.Wrap(err, "register aliyun oss metrics")
+		}
+	}
+	return nil
+}
+
+// observe records an operation started at the given time and finished with err.
+func (m *metrics) observe(op string, start time.Time, err error) {
+	m.ops.WithLabelValues(op).Inc()
+	if err != nil {
+		m.opsFailures.WithLabelValues(op).Inc()
+	}
+	m.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// observe records the operation on the named object, or the directory of listings, in the metrics. With
+// debug_logging enabled it is logged at debug level as well, along with details like the range read given
+// as keyvals. Only names and such details are logged, never credentials or request headers.
+func (b *Bucket) observe(op, name string, start time.Time, err error, keyvals ...interface{}) {
+	b.metrics.observe(op, start, err)
+	if !b.config.DebugLogging {
+		return
+	}
+	kv := append([]interface{}{"msg", "oss operation", "op", op, "object", name, "duration", time.Since(start)}, keyvals...)
+	if err != nil {
+		kv = append(kv, "err", err)
+	}
+	level.Debug(b.logger).Log(kv...)
+}
+
+// countUploaded wraps the body of an upload request to count the bytes sent, limiting its bandwidth if
+// configured. The SDK only learns the length of bodies of a few known types, like bytes.Reader, so the
+// length of such bodies is kept visible to it.
+func (b *Bucket) countUploaded(ctx context.Context, r io.Reader) io.Reader {
+	cr := &countingReader{Reader: b.limitUpload(ctx, r), counter: b.metrics.uploadedBytes}
+	if l, ok := r.(interface{ Len() int }); ok {
+		return &io.LimitedReader{R: cr, N: int64(l.Len())}
+	}
+	return cr
+}
+
+// countingReader adds the bytes read through it to a counter.
+type countingReader struct {
+	io.Reader
+	counter prometheus.Counter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.counter.Add(float64(n))
+	return n, err
+}
+
+// countingReadCloser adds the bytes read through it to a counter as they are read, not when it is created.
+type countingReadCloser struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.counter.Add(float64(n))
+	return n, err
+}
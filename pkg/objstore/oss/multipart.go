@@ -0,0 +1,377 @@
+package oss
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// multipartUpload is a multipart upload in progress, either newly initiated or a retained one being resumed.
+type multipartUpload struct {
+	init alioss.InitiateMultipartUploadResult
+	// initiated is when the upload was initiated, by this attempt or the one it resumes.
+	initiated time.Time
+	// resumable is set for uploads without object metadata, which later attempts can resume.
+	resumable bool
+	// retained holds the parts already uploaded by a previous attempt, by part number.
+	retained map[int]alioss.UploadedPart
+}
+
+// retainedUploads records the multipart uploads the Bucket gave up on while retaining them, so that only
+// those are resumed, never uploads other writers of the same object are still sending parts to.
+type retainedUploads struct {
+	mtx     sync.Mutex
+	uploads map[string]*multipartUpload
+}
+
+func newRetainedUploads() *retainedUploads {
+	return &retainedUploads{uploads: map[string]*multipartUpload{}}
+}
+
+// add records the failed upload, replacing any older one of the same object left to be cleaned up.
+func (r *retainedUploads) add(u *multipartUpload) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.uploads[u.init.Key] = u
+}
+
+// take removes and returns the failed upload of the object, if any, so that a single attempt resumes it.
+func (r *retainedUploads) take(name string) (*multipartUpload, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	u, ok := r.uploads[name]
+	delete(r.uploads, name)
+	return u, ok
+}
+
+// reusable reports whether the retained part with the given number holds size bytes with the given MD5.
+func (u *multipartUpload) reusable(number int, size int64, sum []byte) (alioss.UploadPart, bool) {
+	p, ok := u.retained[number]
+	if !ok || int64(p.Size) != size || !strings.EqualFold(strings.Trim(p.ETag, `"`), hex.EncodeToString(sum)) {
+		return alioss.UploadPart{}, false
+	}
+	return alioss.UploadPart{PartNumber: p.PartNumber, ETag: p.ETag}, true
+}
+
+// initiateMultipartUpload starts a multipart upload of the object. With a multipart retention window
+// configured, the upload of the same object this Bucket gave up on within the window is resumed instead,
+// so parts already uploaded with identical content are not sent again. Uploads still in progress, by this
+// process or another one, are never resumed. Uploads setting object metadata or an explicit content type
+// are never resumed either, as the metadata of a retained upload cannot be changed.
+func (b *Bucket) initiateMultipartUpload(name string, p *uploadParams) (*multipartUpload, error) {
+	opts := p.options()
+	resumable := len(opts) == 0 && p.contentType == ""
+	if b.config.MultipartRetention > 0 && resumable {
+		u, err := b.retainedMultipartUpload(name)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to resume retained multipart upload, starting a new one", "object", name, "err", err)
+		} else if u != nil {
+			return u, nil
+		}
+	}
+	init, err := b.bucket.InitiateMultipartUpload(name, append(opts, b.createOptions(name, p)...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &multipartUpload{init: init, initiated: time.Now(), resumable: resumable}, nil
+}
+
+// retainedMultipartUpload claims the upload of the object retained by a failed attempt within the window.
+func (b *Bucket) retainedMultipartUpload(name string) (*multipartUpload, error) {
+	failed, ok := b.retained.take(name)
+	if !ok || time.Since(failed.initiated) > time.Duration(b.config.MultipartRetention) {
+		return nil, nil
+	}
+	u, err := b.resumeMultipartUpload(name, failed.init.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	u.initiated, u.resumable = failed.initiated, true
+	return u, nil
+}
+
+// resumeMultipartUpload returns the incomplete multipart upload of the object with the given ID along
+// with the parts uploaded to it so far.
+func (b *Bucket) resumeMultipartUpload(name, uploadID string) (*multipartUpload, error) {
+	u := &multipartUpload{
+		init:     alioss.InitiateMultipartUploadResult{Bucket: b.name, Key: name, UploadID: uploadID},
+		retained: map[int]alioss.UploadedPart{},
+	}
+	marker := 0
+	for {
+		res, err := b.bucket.ListUploadedParts(u.init, alioss.PartNumberMarker(marker))
+		if err != nil {
+			return nil, errors.Wrap(err, "list uploaded parts")
+		}
+		for _, p := range res.UploadedParts {
+			u.retained[p.PartNumber] = p
+		}
+		if !res.IsTruncated {
+			break
+		}
+		if marker, err = strconv.Atoi(res.NextPartNumberMarker); err != nil {
+			return nil, errors.Wrap(err, "parse next part number marker")
+		}
+	}
+	level.Info(b.logger).Log("msg", "resuming multipart upload", "object", name, "upload_id", uploadID, "parts", len(u.retained))
+	return u, nil
+}
+
+// uploadPart uploads the next size bytes of r as the given part, reusing a retained part with the same
+// content if there is one. Reusing a part requires r to be seekable, otherwise the part is always uploaded.
+func (b *Bucket) uploadPart(ctx context.Context, u *multipartUpload, r io.Reader, size int64, number int) (alioss.UploadPart, error) {
+	if s, ok := r.(io.ReadSeeker); ok && u.retained[number].PartNumber == number {
+		h := md5.New()
+		n, err := io.CopyN(h, s, size)
+		if err != nil {
+			return alioss.UploadPart{}, err
+		}
+		if p, ok := u.reusable(number, n, h.Sum(nil)); ok {
+			return p, nil
+		}
+		if _, err := s.Seek(-n, io.SeekCurrent); err != nil {
+			return alioss.UploadPart{}, err
+		}
+	}
+	return b.bucket.UploadPart(u.init, b.countUploaded(ctx, r), size, number, b.transferOptions()...)
+}
+
+// uploadParts uploads the next size bytes of r as parts of partSize bytes, up to UploadConcurrency of them
+// at once, and returns the parts ordered by part number. Each part is read with ReadAt through its own
+// section of r, so concurrent parts do not share a read offset. No further parts are started once one
+// failed or ctx was cancelled, and the first error is returned after the parts in flight finished. With
+// CRC validation enabled, the CRC64 of the uploaded content is returned, combined from those of the parts.
+func (b *Bucket) uploadParts(ctx context.Context, u *multipartUpload, r io.ReadSeeker, size, partSize int64) ([]alioss.UploadPart, uint64, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, err
+	}
+	ra := r.(io.ReaderAt)
+	concurrency := b.config.UploadConcurrency
+	if concurrency == 0 {
+		concurrency = defaultUploadConcurrency
+	}
+
+	var (
+		parts    = make([]alioss.UploadPart, (size+partSize-1)/partSize)
+		crcs     = make([]uint64, len(parts))
+		sizes    = make([]int64, len(parts))
+		slots    = make(chan struct{}, concurrency)
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		firstErr error
+	)
+	for i := range parts {
+		slots <- struct{}{}
+		mtx.Lock()
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = errors.Wrap(ctx.Err(), "multi-part upload cancelled")
+		}
+		failed := firstErr != nil
+		mtx.Unlock()
+		if failed {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			off := int64(i) * partSize
+			n := partSize
+			if off+n > size {
+				n = size - off
+			}
+			src := &sourceReader{ReadSeeker: io.NewSectionReader(ra, base+off, n)}
+			var (
+				part alioss.UploadPart
+				crc  uint64
+				err  error
+			)
+			if b.config.EnableCRCValidation {
+				if crc, err = seekableCRC64(src, n); err != nil {
+					err = src.wrap(err, "failed to checksum upload source")
+				}
+			}
+			if err == nil {
+				err = b.retryUpload(ctx, src, func() (err error) {
+					if part, err = b.uploadPart(ctx, u, src, n, i+1); err != nil {
+						return src.wrap(err, fmt.Sprintf("failed to upload multi-part chunk %d", i+1))
+					}
+					return nil
+				})
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			parts[i], crcs[i], sizes[i] = part, crc, n
+		}(i)
+	}
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = errors.Wrap(ctx.Err(), "multi-part upload cancelled")
+	}
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	return parts, combineCRC64(crcs, sizes), nil
+}
+
+// abortMultipartUpload aborts the multipart upload after a failed attempt. With a multipart retention
+// window configured the upload is left in place instead, to be resumed by the next attempt of this Bucket
+// or removed by CleanupMultipartUploads.
+func (b *Bucket) abortMultipartUpload(u *multipartUpload) error {
+	if b.config.MultipartRetention > 0 {
+		level.Info(b.logger).Log("msg", "retaining failed multipart upload", "object", u.init.Key, "upload_id", u.init.UploadID)
+		if u.resumable {
+			b.retained.add(u)
+		}
+		return nil
+	}
+	return b.bucket.AbortMultipartUpload(u.init)
+}
+
+// abortFailedUpload aborts the multipart upload after it failed with err and returns err wrapped with msg.
+// Failing to abort the upload is only logged, so that callers still see why the upload failed.
+func (b *Bucket) abortFailedUpload(u *multipartUpload, err error, msg string) error {
+	if aerr := b.abortMultipartUpload(u); aerr != nil {
+		level.Warn(b.logger).Log("msg", "failed to abort multi-part upload", "object", u.init.Key, "upload_id", u.init.UploadID, "err", aerr)
+	}
+	return errors.Wrap(err, msg)
+}
+
+// listMultipartUploads calls f for every incomplete multipart upload of objects with the given prefix.
+func (b *Bucket) listMultipartUploads(prefix string, f func(alioss.UncompletedUpload) error) error {
+	opts := []alioss.Option{alioss.Prefix(prefix)}
+	for {
+		res, err := b.bucket.ListMultipartUploads(opts...)
+		if err != nil {
+			return errors.Wrap(err, "list multipart uploads")
+		}
+		for _, u := range res.Uploads {
+			if err := f(u); err != nil {
+				return err
+			}
+		}
+		if !res.IsTruncated {
+			return nil
+		}
+		opts = []alioss.Option{
+			alioss.Prefix(prefix),
+			alioss.KeyMarker(res.NextKeyMarker),
+			alioss.UploadIDMarker(res.NextUploadIDMarker),
+		}
+	}
+}
+
+// CleanupMultipartUploads aborts the incomplete multipart uploads initiated longer than the multipart
+// retention window ago, freeing the storage held by their parts. Uploads of keys rejected by the key
+// filters are left alone. It does nothing unless a retention window is configured.
+func (b *Bucket) CleanupMultipartUploads(ctx context.Context) error {
+	if b.config.MultipartRetention <= 0 {
+		return nil
+	}
+	return b.AbortIncompleteMultipartUploads(ctx, time.Duration(b.config.MultipartRetention))
+}
+
+// MultipartUpload is an incomplete multipart upload, either in progress or left behind by a failed or
+// cancelled upload.
+type MultipartUpload struct {
+	// Name is the logical key of the object being uploaded.
+	Name      string
+	UploadID  string
+	Initiated time.Time
+}
+
+// ListMultipartUploads returns the incomplete multipart uploads of the bucket. Uploads of keys rejected by
+// the key filters or without a logical key are left out.
+func (b *Bucket) ListMultipartUploads(ctx context.Context) ([]MultipartUpload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	var uploads []MultipartUpload
+	err = b.listMultipartUploads(b.physical(""), func(u alioss.UncompletedUpload) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !b.keyAllowed(u.Key) {
+			return nil
+		}
+		if name, ok := b.logical(u.Key); ok {
+			uploads = append(uploads, MultipartUpload{Name: name, UploadID: u.UploadID, Initiated: u.Initiated})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, withClockSkewHint(err)
+	}
+	return uploads, nil
+}
+
+// AbortIncompleteMultipartUploads aborts the incomplete multipart uploads initiated longer than olderThan
+// ago, freeing the storage held by their parts, which is billed until then. Uploads still in progress are
+// aborted as well once old enough, so olderThan must exceed the time the longest upload may take. Uploads
+// of keys rejected by the key filters or without a logical key, e.g. outside of the prefix, are left alone.
+func (b *Bucket) AbortIncompleteMultipartUploads(ctx context.Context, olderThan time.Duration) error {
+	if olderThan <= 0 {
+		return errors.Errorf("invalid age %s of multipart uploads to abort, it must be positive", olderThan)
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	aborted := 0
+	err = b.listMultipartUploads(b.physical(""), func(u alioss.UncompletedUpload) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, ok := b.logical(u.Key); !ok || !b.keyAllowed(u.Key) || time.Since(u.Initiated) <= olderThan {
+			return nil
+		}
+		init := alioss.InitiateMultipartUploadResult{Bucket: b.name, Key: u.Key, UploadID: u.UploadID}
+		start := time.Now()
+		err := b.retry(ctx, func() error { return b.bucket.AbortMultipartUpload(init) })
+		if err != nil && !b.IsObjNotFoundErr(err) {
+			err = errors.Wrapf(err, "abort stale multipart upload %s of %s", u.UploadID, u.Key)
+			b.audit(OpAbortMultipartUpload, u.Key, 0, start, err)
+			return err
+		}
+		b.audit(OpAbortMultipartUpload, u.Key, 0, start, nil)
+		aborted++
+		return nil
+	})
+	if aborted > 0 {
+		level.Info(b.logger).Log("msg", "aborted stale multipart uploads", "count", aborted, "older_than", olderThan)
+	}
+	return withClockSkewHint(err)
+}
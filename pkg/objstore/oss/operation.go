@@ -0,0 +1,43 @@
+package oss
+
+import (
+	"net/http"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// operationClient sends the requests of an operation whose transport is overridden.
+type operationClient struct {
+	transport http.RoundTripper
+	bucket    *alioss.Bucket
+}
+
+// newOperationClients creates the clients of the operations whose transport is overridden. Their
+// transports enforce the same timeouts and limits as that of the Bucket.
+func (b *Bucket) newOperationClients() error {
+	if len(b.operationTransports) == 0 {
+		return nil
+	}
+	b.operations = map[string]operationClient{}
+	for op, rt := range b.operationTransports {
+		rt = b.wrapTransport(rt)
+		bk, err := b.newClient(rt)
+		if err != nil {
+			return err
+		}
+		b.operations[op] = operationClient{transport: rt, bucket: bk}
+	}
+	return nil
+}
+
+// operation returns the Bucket running the operation observed under the given name, which sends its
+// requests through the transport overriding that of the operation, if any.
+func (b *Bucket) operation(op string) *Bucket {
+	c, ok := b.operations[op]
+	if !ok {
+		return b
+	}
+	view := *b
+	view.client, view.bucket, view.transport = &c.bucket.Client, c.bucket, c.transport
+	return &view
+}
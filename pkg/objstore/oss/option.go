@@ -0,0 +1,38 @@
+package oss
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option overrides behavior of Bucket.
+type Option interface {
+	apply(*Bucket)
+}
+
+type optionFunc func(*Bucket)
+
+func (f optionFunc) apply(b *Bucket) {
+	f(b)
+}
+
+// WithAuditor makes the Bucket report every mutating operation to the given Auditor.
+func WithAuditor(a Auditor) Option {
+	return optionFunc(func(b *Bucket) {
+		b.auditor = a
+	})
+}
+
+// WithRegisterer makes the Bucket register its metrics with the given registerer.
+func WithRegisterer(reg prometheus.Registerer) Option {
+	return optionFunc(func(b *Bucket) {
+		b.registerer = reg
+	})
+}
+
+// WithKeyTransform makes the Bucket store objects under the physical keys the given KeyTransform maps the
+// logical keys of callers to. Key filters apply to the physical keys.
+func WithKeyTransform(t KeyTransform) Option {
+	return optionFunc(func(b *Bucket) {
+		b.keys = t
+	})
+}
@@ -0,0 +1,51 @@
+package oss
+
+import (
+	"net/http"
+)
+
+// withTransport makes the Bucket send its requests through the given transport.
+func withTransport(rt http.RoundTripper) Option {
+	return optionFunc(func(b *Bucket) {
+		b.transport = rt
+	})
+}
+
+// withOperationTransport makes the Bucket send the requests of the operation observed under the given name,
+// e.g. "get" or "upload", through the given transport, subject to the same timeouts and limits.
+func withOperationTransport(op string, rt http.RoundTripper) Option {
+	return optionFunc(func(b *Bucket) {
+		if b.operationTransports == nil {
+			b.operationTransports = map[string]http.RoundTripper{}
+		}
+		b.operationTransports[op] = rt
+	})
+}
+
+// withPartSize makes the Bucket split multipart uploads into parts of the given size.
+func withPartSize(size int64) Option {
+	return optionFunc(func(b *Bucket) {
+		b.partSize = size
+	})
+}
+
+// withMaxParts makes the Bucket upload at most the given number of parts per multipart upload.
+func withMaxParts(n int) Option {
+	return optionFunc(func(b *Bucket) {
+		b.maxParts = n
+	})
+}
+
+// withMaxCopySize makes the Bucket copy objects larger than the given size part by part.
+func withMaxCopySize(size int64) Option {
+	return optionFunc(func(b *Bucket) {
+		b.maxCopySize = size
+	})
+}
+
+// withMetadataURL makes the Bucket fetch RAM role credentials from the given metadata service base URL.
+func withMetadataURL(u string) Option {
+	return optionFunc(func(b *Bucket) {
+		b.metadataURL = u
+	})
+}
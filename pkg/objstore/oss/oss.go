@@ -1,14 +1,21 @@
 package oss
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"hash/crc64"
 	"io"
 	"io/ioutil"
-	"math"
 	"math/rand"
 	"net/http"
 	"os"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -16,22 +23,246 @@ import (
 
 	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
 	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v2"
 )
 
-// Part size for multi part upload.
+// Default part size for multi part upload.
 const PartSize = 1024 * 1024 * 128
 
+// MinPartSize is the minimum size OSS accepts for the parts of a multipart upload but the last one.
+const MinPartSize = 100 * 1024
+
+// Default number of parts of a multipart upload uploaded at once.
+const defaultUploadConcurrency = 4
+
+// MaxListObjectsMaxKeys is the maximum number of keys OSS returns per page of a listing.
+const MaxListObjectsMaxKeys = 1000
+
+// MaxParts is the maximum number of parts OSS accepts for a multipart upload.
+const MaxParts = 10000
+
+// MaxPutObjectSize is the size of the largest object OSS accepts with a single PutObject request.
+const MaxPutObjectSize = 5 * 1024 * 1024 * 1024
+
 // Config stores the configuration for oss bucket.
 type Config struct {
 	Endpoint        string `yaml:"endpoint"`
 	Bucket          string `yaml:"bucket"`
 	AccessKeyID     string `yaml:"access_key_id"`
 	AccessKeySecret string `yaml:"access_key_secret"`
+	// Prefix stores every object under this directory of the bucket, e.g. to isolate several deployments
+	// sharing one bucket, as NewPrefixedBucket does. Keys passed to and listed by the Bucket are relative
+	// to it. It must not start with a slash.
+	Prefix string `yaml:"prefix"`
+	// SecurityToken is the token of temporary STS credentials, e.g. those of a RAM role, sent along with
+	// the access key ID and secret. Leave it empty for permanent credentials.
+	SecurityToken string `yaml:"security_token"`
+	// RAMRole is the name of the RAM role bound to the ECS instance whose temporary credentials, fetched from
+	// the instance metadata service and refreshed ahead of their expiration, are used when neither
+	// access_key_id nor access_key_secret are set.
+	RAMRole string `yaml:"ram_role"`
+	// CredentialsFile is a YAML or JSON file with the access_key_id, access_key_secret, security_token and
+	// expiration of temporary credentials, used instead of the static ones and read again ahead of their
+	// expiration, e.g. as they are rotated by a sidecar. Without expiration, it is read on every refresh interval.
+	CredentialsFile string `yaml:"credentials_file"`
+	// CredentialsRefreshInterval is how often the expiration of refreshed credentials is checked. Defaults to 1m.
+	CredentialsRefreshInterval model.Duration `yaml:"credentials_refresh_interval"`
+	// CredentialsExpirySkew is how long before their expiration credentials are renewed. Defaults to 5m.
+	CredentialsExpirySkew model.Duration `yaml:"credentials_expiry_skew"`
+	// KeyAllowRegex and KeyDenyRegex optionally restrict the object keys the bucket may operate on.
+	// A key must match the allow expression (when set) and must not match the deny expression (when set).
+	KeyAllowRegex string `yaml:"key_allow_regex"`
+	KeyDenyRegex  string `yaml:"key_deny_regex"`
+	// SmallObjectThreshold enables serving GetRange of objects of at most this many bytes from a single
+	// full GET, cached in memory. Zero disables it.
+	SmallObjectThreshold int64 `yaml:"small_object_threshold"`
+	// SmallObjectCacheSize caps the memory in bytes used for caching small objects. Defaults to 64MiB.
+	SmallObjectCacheSize int64 `yaml:"small_object_cache_size"`
+	// SHA256Metadata stores the SHA256 of uploaded objects as x-oss-meta-sha256, to be checked with VerifySHA256.
+	// Files and strings are hashed before they are uploaded, other readers are hashed while streaming and
+	// get the metadata set by copying the object onto itself once uploaded in multiple parts.
+	SHA256Metadata bool `yaml:"sha256_metadata"`
+	// IterDirectoryKeys selects how Iter reports keys ending with the delimiter, e.g. "dir/" created by tools
+	// emulating directories. With "prefix", the default, such a key shows up as the directory entry of its
+	// parent, like any common prefix, and is never returned by iterating the directory it names. With "skip",
+	// directories made up of nothing but such a key are not returned at all, at the cost of one extra list
+	// request per directory entry. Recursive iterations return such keys as objects either way.
+	IterDirectoryKeys string `yaml:"iter_directory_keys"`
+	// VerifyPartNumbers checks that the parts of a multipart upload are numbered 1..N without gaps or
+	// duplicates before completing it, failing the upload instead of writing a corrupted object.
+	VerifyPartNumbers bool `yaml:"verify_part_numbers"`
+	// EnableCRCValidation checks the CRC64 of uploaded objects reported by OSS against the one computed
+	// locally of their content, failing uploads corrupted in transit with an error satisfying
+	// IsChecksumMismatchErr. It costs a HEAD request per upload and reading files and strings twice.
+	EnableCRCValidation bool `yaml:"enable_crc_validation"`
+	// IdentityEncoding requests objects with "Accept-Encoding: identity", so that gateways in between do not
+	// compress responses and reads always return the raw stored bytes, keeping checksums consistent.
+	IdentityEncoding bool `yaml:"identity_encoding"`
+	// PartSize is the size in bytes of the parts of multipart uploads, 128MiB when zero. Streamed uploads
+	// hold one part in memory, so smaller parts lower the memory used by concurrent uploads.
+	PartSize uint64 `yaml:"part_size"`
+	// MultipartThreshold is the size in bytes from which uploads from files, strings and byte slices use a
+	// multipart upload, smaller ones are sent with a single PutObject. The part size when zero. Streamed
+	// uploads hold at most one part in memory, so they use a multipart upload from the smaller of the two.
+	MultipartThreshold uint64 `yaml:"multipart_threshold"`
+	// UploadConcurrency is the maximum number of parts of a multipart upload from a file, string or byte
+	// slice uploaded at once, 4 when zero. Streamed uploads always send one part at a time.
+	UploadConcurrency int `yaml:"upload_concurrency"`
+	// AutoPartSize doubles the part size of uploads from files and strings until they fit in the parts
+	// allowed by OSS, instead of failing them. Streamed uploads cannot be resized and fail once they
+	// exceed the limit either way.
+	AutoPartSize bool `yaml:"auto_part_size"`
+	// DedupListingKeys skips an entry of a listing page repeating the last entry of the previous page, as
+	// returned by some OSS compatible gateways on marker edge cases, so Iter does not report it twice.
+	DedupListingKeys bool `yaml:"dedup_listing_keys"`
+	// ReadRepairAttempts makes Get check objects against the CRC64 checksum computed by OSS and fetch them
+	// again up to this many times on a mismatch, before failing with an error satisfying
+	// IsChecksumMismatchErr. The checksum is only known once the whole object was read, so Get buffers
+	// objects in memory before returning them when set. Zero disables the check.
+	ReadRepairAttempts int `yaml:"read_repair_attempts"`
+	// ProtectedTagKey makes Delete refuse to delete objects tagged with this key, whatever the tag value.
+	// Checking the tags costs one extra request per deletion.
+	ProtectedTagKey string `yaml:"protected_tag_key"`
+	// TrafficLimitBytesPerSec caps the bandwidth of each object upload and download request, part uploads
+	// included, so that large transfers do not saturate shared links. OSS accepts limits between 100KiB/s
+	// and 100MiB/s. Zero disables the limit.
+	TrafficLimitBytesPerSec int64 `yaml:"traffic_limit_bytes_per_sec"`
+	// UploadBandwidthLimit and DownloadBandwidthLimit cap the bytes per second sent by uploads and read from
+	// downloads. Unlike traffic_limit_bytes_per_sec, which OSS enforces per request, they are enforced by the
+	// Bucket across all its concurrent transfers and allow any limit. Zero disables the limit.
+	UploadBandwidthLimit   int64 `yaml:"upload_bandwidth_limit"`
+	DownloadBandwidthLimit int64 `yaml:"download_bandwidth_limit"`
+	// MultipartRetention keeps multipart uploads that failed for this long instead of aborting them, so a
+	// later upload of the same object by the same Bucket resumes them and skips the parts already uploaded.
+	// Stale uploads, including those of restarted processes, are removed by CleanupMultipartUploads.
+	// Retained parts are billed as regular storage until then, so a long window trades storage cost for
+	// less re-uploading. Zero aborts failed uploads immediately.
+	MultipartRetention model.Duration `yaml:"multipart_retention"`
+	// EnableResumableUpload uploads files in several parts with the SDK's UploadFile, which records the
+	// upload in a checkpoint file in CheckpointDir, so that uploading the same, unmodified file to the same
+	// object again after a failure only sends the parts missing, even after a restart. The multipart uploads
+	// of failed attempts are left in place to be resumed, regardless of the multipart retention window, and
+	// checkpoints are removed once their upload completed. Uploads setting tags, user metadata, an explicit
+	// content type, a callback or preconditions are uploaded as usual.
+	EnableResumableUpload bool `yaml:"enable_resumable_upload"`
+	// CheckpointDir is the local directory keeping the checkpoint files of resumable uploads, created if
+	// missing. Required by EnableResumableUpload.
+	CheckpointDir string `yaml:"checkpoint_dir"`
+	// ParallelRange splits large GetRange reads into concurrent ranged GETs, lowering their latency.
+	ParallelRange ParallelRangeConfig `yaml:"parallel_range"`
+	// StrictRangeValidation looks up the size of the object before every GetRange to clamp the range to
+	// it, rather than issuing the ranged GET right away and requesting the range again clamped in the rare
+	// case it ends past the object. The small object cache and parallel range reads look it up regardless.
+	StrictRangeValidation bool `yaml:"strict_range_validation"`
+	// Retry configures retrying failed idempotent requests.
+	Retry RetryConfig `yaml:"retry"`
+	// RetryInvalidObjectState retries reads failing with InvalidObjectState, as returned while objects
+	// transition between storage classes, like transient errors. Archived objects fail the same way until
+	// restored, so reads of those are delayed by the retries before failing. Requires retries configured.
+	RetryInvalidObjectState bool `yaml:"retry_invalid_object_state"`
+	// ListTimeout limits the time each page of a listing may take, rather than the whole listing, so that
+	// a hung page fails without cutting off long listings making progress. Zero means no limit.
+	ListTimeout model.Duration `yaml:"list_timeout"`
+	// RequestTimeout limits the time Upload and its variants, Delete, Get, GetRange, Exists, Attributes,
+	// Iter, IterWithAttributes and IterObjects may take, their retries and, for reads, reading the object
+	// included, on top of the context they are called with. Every request is limited to it as well, as the
+	// SDK cannot cancel requests. Zero means no limit.
+	RequestTimeout model.Duration `yaml:"request_timeout"`
+	// MaxConcurrentRequests caps the operations of the Bucket in progress at once, so that heavy load does
+	// not get throttled by OSS. Every operation sending requests to OSS takes a slot, waiting for one until
+	// the context it is called with is done. Readers returned by Get, GetRange, GetForUpdate, GetConditional
+	// and GetVersion hold their slot until closed, while Iter, IterWithAttributes, IterObjects and
+	// IterVersions only hold one while listing each page, so that their callbacks may use the Bucket.
+	// Unlike WithLimiter, which caps the HTTP requests of possibly several buckets, a multipart upload or
+	// copy and a DeleteMultiple count once. Zero means no limit.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	// ListRetry configures retrying failed listing pages, e.g. after ListTimeout elapsed, instead of Retry.
+	// Pages are retried as set by Retry unless ListRetry.MaxRetries is set.
+	ListRetry RetryConfig `yaml:"list_retry"`
+	// ListObjectsMaxKeys is the number of keys requested per page of a listing, up to 1000. Zero means
+	// 1000, saving round-trips over the default of 100 keys of OSS.
+	ListObjectsMaxKeys int `yaml:"list_objects_max_keys"`
+	// MaxListBufferObjects caps the entries of a listing page held in memory at once by Iter,
+	// IterWithAttributes, IterObjects and IterVersions, and the keys requested per page to it. Endpoints
+	// ignoring the requested max-keys may still return larger pages: object listings fail on those, while
+	// IterVersions, which sorts each page to merge versions and delete markers back into key order, passes
+	// their versions unsorted instead, all object versions first and then all delete markers, as sorting
+	// would copy the page. Zero means no limit.
+	MaxListBufferObjects int `yaml:"max_list_buffer_objects"`
+	// StorageClass is the storage class of uploaded objects: Standard, IA, Archive or ColdArchive. Empty
+	// uses the storage class of the bucket. Archive and ColdArchive objects cannot be read until restored,
+	// and streamed uploads with sha256_metadata fail on them, as setting the checksum rewrites the object.
+	StorageClass string `yaml:"storage_class"`
+	// ObjectACL is the canned ACL of uploaded objects: private, public-read, public-read-write or default,
+	// which inherits the ACL of the bucket. Empty sends none, leaving it to OSS, which applies default.
+	ObjectACL string `yaml:"object_acl"`
+	// SSEConfig configures the server-side encryption of uploaded objects.
+	SSEConfig SSEConfig `yaml:"sse_config"`
+	// HTTPConfig configures the HTTP transport of the OSS client.
+	HTTPConfig HTTPConfig `yaml:"http_config"`
+	// UseInternalEndpoint sends requests to the internal endpoint of the region of the endpoint, which may
+	// also be given as a region ID, e.g. cn-hangzhou. Traffic to internal endpoints from within the region
+	// is not billed for. Endpoints other than those of OSS regions are used as they are.
+	UseInternalEndpoint bool `yaml:"use_internal_endpoint"`
+	// CName makes the endpoint the custom domain bound to the bucket, e.g. oss.example.com, to which
+	// requests are sent without the bucket name prepended to the host.
+	CName bool `yaml:"cname"`
+	// Insecure makes requests to endpoints without a scheme use plain HTTP rather than HTTPS, e.g. for a
+	// local OSS compatible server in tests. It cannot be combined with an https:// endpoint.
+	Insecure bool `yaml:"insecure"`
+	// DebugLogging logs every Upload, Get, GetRange, Delete, Iter, Exists and Attributes at debug level with
+	// the object name, the range read, the bytes uploaded, the duration and the error, if any.
+	DebugLogging bool `yaml:"debug_logging"`
+	// CheckBucketExists makes NewBucket fail with a clear error if the bucket does not exist, rather than
+	// the first write to it. It costs a request when the Bucket is created.
+	CheckBucketExists bool `yaml:"check_bucket_exists"`
+	// CreateBucket allows EnsureBucket, and with CheckBucketExists NewBucket, to create the bucket if it
+	// does not exist.
+	CreateBucket bool `yaml:"create_bucket"`
+	// ReadOnly makes every method modifying the bucket fail with an error satisfying IsReadOnlyErr without
+	// sending any request, as a guardrail when pointing tools at production buckets. Reads still work.
+	ReadOnly bool `yaml:"read_only"`
 }
 
+// Environment variables holding the credentials used when the config has none.
+const (
+	envAccessKeyID     = "ALIYUN_ACCESS_KEY_ID"
+	envAccessKeySecret = "ALIYUN_ACCESS_KEY_SECRET"
+	envSecurityToken   = "ALIYUN_SECURITY_TOKEN"
+)
+
+// Supported values of Config.IterDirectoryKeys.
+const (
+	IterDirectoryKeysPrefix = "prefix"
+	IterDirectoryKeysSkip   = "skip"
+)
+
+// Supported values of Config.StorageClass.
+const (
+	StorageClassStandard    = string(alioss.StorageStandard)
+	StorageClassIA          = string(alioss.StorageIA)
+	StorageClassArchive     = string(alioss.StorageArchive)
+	StorageClassColdArchive = "ColdArchive"
+)
+
+// Bandwidth limits accepted by OSS for Config.TrafficLimitBytesPerSec.
+const (
+	MinTrafficLimitBytesPerSec = 100 * 1024
+	MaxTrafficLimitBytesPerSec = 100 * 1024 * 1024
+)
+
+// Default size of the small objects cache.
+const defaultSmallObjectCacheSize = 64 * 1024 * 1024
+
 // Bucket implements the store.Bucket interface.
 type Bucket struct {
 	name   string
@@ -39,6 +270,51 @@ type Bucket struct {
 	client *alioss.Client
 	config Config
 	bucket *alioss.Bucket
+
+	allowKeys *regexp.Regexp
+	denyKeys  *regexp.Regexp
+
+	component string
+	auditor   Auditor
+
+	// registerer receives the metrics, which are not registered anywhere when it is nil.
+	registerer prometheus.Registerer
+	metrics    *metrics
+
+	smallObjects *objectCache
+	// retained are the failed multipart uploads left for the next attempt to resume.
+	retained *retainedUploads
+
+	keys KeyTransform
+
+	// partSize is the size of the parts of multipart uploads.
+	partSize int64
+	// maxParts is the maximum number of parts of multipart uploads.
+	maxParts int
+	// maxCopySize is the size of the largest object copied with a single request.
+	maxCopySize int64
+
+	// transport replaces the SDK's HTTP transport when set.
+	transport http.RoundTripper
+	// operationTransports replace the transport of single operations, by the name they are observed under,
+	// allowing tests to inject faults into them. operations holds the clients sending their requests.
+	operationTransports map[string]http.RoundTripper
+	operations          map[string]operationClient
+	// limiter caps the concurrent requests, possibly together with other buckets.
+	limiter *Limiter
+	// sem caps the operations in progress, nil when unlimited.
+	sem *semaphore.Weighted
+	// uploadLimiter and downloadLimiter cap the bandwidth of transfers, nil when unlimited.
+	uploadLimiter   *rate.Limiter
+	downloadLimiter *rate.Limiter
+	// shouldRetry replaces IsRetryable in deciding which failed requests are retried.
+	shouldRetry ShouldRetryFunc
+
+	credentialsProvider CredentialsProvider
+	// metadataURL is the base URL of RAM role credentials of the ECS metadata service.
+	metadataURL string
+	// credentials are the refreshed credentials requests are signed with, nil when static ones are used.
+	credentials *refreshingCredentials
 }
 
 func NewTestBucket(t testing.TB) (objstore.Bucket, func(), error) {
@@ -62,96 +338,685 @@ func NewTestBucket(t testing.TB) (objstore.Bucket, func(), error) {
 	return NewTestBucketFromConfig(t, c, false)
 }
 
-func calculateChunks(name string, r io.Reader) (int, int64, error) {
-	switch r.(type) {
+// calculateChunks returns the number of full parts of partSize bytes of an upload from r, and the number of
+// bytes remaining after them, which make up a smaller last part unless zero. An upload of an exact multiple
+// of partSize bytes thus has no remainder, with its last part being a full one. Readers of unknown size are
+// reported with -1 full parts, to be streamed.
+func calculateChunks(name string, r io.Reader, partSize int64) (fullParts int, remainder int64, err error) {
+	switch f := r.(type) {
 	case *os.File:
-		f, _ := r.(*os.File)
-		if fileInfo, err := f.Stat(); err == nil {
-			s := fileInfo.Size()
-			return int(math.Floor(float64(s) / PartSize)), s % PartSize, nil
+		fileInfo, err := f.Stat()
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "stat upload source of %s", name)
+		}
+		// Size is meaningless for pipes, sockets and devices, so those are streamed instead.
+		if !fileInfo.Mode().IsRegular() {
+			return -1, 0, nil
 		}
+		chunks, last := splitChunks(fileInfo.Size(), partSize)
+		return chunks, last, nil
 	case *strings.Reader:
-		f, _ := r.(*strings.Reader)
-		return int(math.Floor(float64(f.Size()) / PartSize)), f.Size() % PartSize, nil
+		chunks, last := splitChunks(int64(f.Len()), partSize)
+		return chunks, last, nil
+	case *bytes.Reader:
+		chunks, last := splitChunks(int64(f.Len()), partSize)
+		return chunks, last, nil
 	}
-	return -1, 0, errors.New("unsupported implement of io.Reader")
+	return -1, 0, nil
+}
+
+// splitChunks returns the number of full chunks of partSize bytes in size bytes and the size of the remainder.
+// Integer division keeps both consistent, float division rounds up sizes just below a large multiple.
+func splitChunks(size, partSize int64) (int, int64) {
+	return int(size / partSize), size % partSize
 }
 
-// Upload the contents of the reader as an object into the bucket.
+// EstimateUpload returns the number of parts an upload of size bytes is split into with the given part
+// size, and the size of the last part. Objects smaller than a part are uploaded with a single request and
+// count as one part. Part counts above MaxParts are rejected by OSS.
+func EstimateUpload(size int64, partSize int64) (parts int, lastPartSize int64) {
+	chunks, last := splitChunks(size, partSize)
+	switch {
+	case chunks == 0:
+		return 1, size
+	case last == 0:
+		return chunks, partSize
+	default:
+		return chunks + 1, last
+	}
+}
+
+// checkKey returns an error if the key is rejected by the configured key filters.
+func (b *Bucket) checkKey(name string) error {
+	if !b.keyAllowed(name) {
+		return errors.Errorf("object key %q is not allowed by the configured key filters", name)
+	}
+	return nil
+}
+
+// checkWritable returns an error if the bucket is read-only.
+func (b *Bucket) checkWritable() error {
+	if b.config.ReadOnly {
+		return errors.Wrapf(errReadOnly, "oss bucket %s is read-only", b.name)
+	}
+	return nil
+}
+
+func (b *Bucket) keyAllowed(name string) bool {
+	if b.allowKeys != nil && !b.allowKeys.MatchString(name) {
+		return false
+	}
+	return b.denyKeys == nil || !b.denyKeys.MatchString(name)
+}
+
+// Upload the contents of the reader as an object into the bucket. Regular files, strings.Reader and
+// bytes.Reader are uploaded knowing their size, any other reader is streamed one part at a time.
+// Cancelling ctx stops the upload before its next part and aborts it; parts being sent are not
+// interrupted, as the OSS SDK does not support contexts.
 func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
-	chunksnum, lastslice, err := calculateChunks(name, r)
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, &uploadParams{})
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	return err
+}
+
+// forget drops any locally cached state of the object after it was modified.
+func (b *Bucket) forget(name string) {
+	if b.smallObjects != nil {
+		b.smallObjects.remove(name)
+	}
+}
+
+// uploadParams holds per-call upload settings.
+type uploadParams struct {
+	// callback is the encoded x-oss-callback header value, sent with the request completing the upload.
+	callback string
+	// callbackResponse receives the callback server's response relayed by OSS.
+	callbackResponse []byte
+	// sha256 is the hex encoded SHA256 of the content, stored as object metadata when set.
+	sha256 string
+	// tags are set on the object when it is created.
+	tags []alioss.Tag
+	// meta is the user metadata stored with the object, by lower cased key.
+	meta map[string]string
+	// contentType overrides the content type detected from the object name when set.
+	contentType string
+	// ifMatch and ifNoneMatch are sent as If-Match and If-None-Match with the request committing the
+	// object when set, so that endpoints supporting conditional writes refuse to overwrite a changed object.
+	ifMatch, ifNoneMatch string
+	// checkETag checks the object against ifMatch, or its absence for ifNoneMatch, before uploading it.
+	checkETag bool
+}
+
+// options returns the SDK options applying the settings to the request creating the object.
+func (p *uploadParams) options() []alioss.Option {
+	opts := metaOptions(p.meta)
+	if p.sha256 != "" {
+		opts = append(opts, alioss.Meta(sha256MetaKey, p.sha256))
+	}
+	if len(p.tags) > 0 {
+		opts = append(opts, alioss.SetTagging(alioss.Tagging{Tags: p.tags}))
+	}
+	return opts
+}
+
+// conditionHeaders returns the headers of the preconditions of the request committing the object.
+func (p *uploadParams) conditionHeaders() map[string]string {
+	h := map[string]string{}
+	if p.ifMatch != "" {
+		h[alioss.HTTPHeaderIfMatch] = p.ifMatch
+	}
+	if p.ifNoneMatch != "" {
+		h[alioss.HTTPHeaderIfNoneMatch] = p.ifNoneMatch
+	}
+	return h
+}
+
+// conditionOptions returns the SDK options of the preconditions of the request committing the object.
+func (p *uploadParams) conditionOptions() []alioss.Option {
+	var opts []alioss.Option
+	if p.ifMatch != "" {
+		opts = append(opts, alioss.IfMatch(p.ifMatch))
+	}
+	if p.ifNoneMatch != "" {
+		opts = append(opts, alioss.IfNoneMatch(p.ifNoneMatch))
+	}
+	return opts
+}
+
+// putObject uploads the object with a single request.
+func (b *Bucket) putObject(ctx context.Context, name string, r io.Reader, p *uploadParams) error {
+	opts := append(p.options(), b.transferOptions()...)
+	opts = append(opts, b.createOptions(name, p)...)
+	opts = append(opts, p.conditionOptions()...)
+	if p.callback != "" {
+		opts = append(opts, alioss.Callback(p.callback))
+	}
+	resp, err := b.bucket.DoPutObject(&alioss.PutObjectRequest{ObjectKey: name, Reader: b.countUploaded(ctx, r)}, opts)
+	if resp != nil {
+		defer runutil.CloseWithLogOnErr(b.logger, resp.Body, "oss put object response body")
+	}
 	if err != nil {
 		return err
 	}
+	if p.callback != "" {
+		if p.callbackResponse, err = ioutil.ReadAll(resp.Body); err != nil {
+			return errors.Wrap(err, "read callback response")
+		}
+	}
+	return nil
+}
+
+// completeMultipartUpload completes the multipart upload from the given parts.
+func (b *Bucket) completeMultipartUpload(init alioss.InitiateMultipartUploadResult, parts []alioss.UploadPart, p *uploadParams) error {
+	if b.config.VerifyPartNumbers {
+		if err := verifyPartNumbers(parts); err != nil {
+			return errors.Wrapf(err, "internal error: invalid parts of multipart upload %s of %s", init.UploadID, init.Key)
+		}
+	}
+	if p.callback != "" {
+		return b.completeMultipartUploadWithCallback(init, parts, p)
+	}
+	_, err := b.bucket.CompleteMultipartUpload(init, parts, p.conditionOptions()...)
+	return err
+}
+
+// verifyPartNumbers returns an error unless the parts, in any order, are numbered 1..N.
+func verifyPartNumbers(parts []alioss.UploadPart) error {
+	numbers := make([]int, 0, len(parts))
+	for _, p := range parts {
+		numbers = append(numbers, p.PartNumber)
+	}
+	sort.Ints(numbers)
+	for i, n := range numbers {
+		if n != i+1 {
+			return errors.Errorf("part numbers %v do not form the sequence 1..%d", numbers, len(numbers))
+		}
+	}
+	return nil
+}
+
+// multipartThreshold returns the size from which objects are uploaded with a multipart upload.
+func (b *Bucket) multipartThreshold() int64 {
+	if b.config.MultipartThreshold != 0 {
+		return int64(b.config.MultipartThreshold)
+	}
+	return b.partSize
+}
+
+// partSizeFor returns the part size to upload an object of the given size with.
+func (b *Bucket) partSizeFor(name string, size int64) (int64, error) {
+	partSize := b.partSize
+	for parts, _ := EstimateUpload(size, partSize); parts > b.maxParts; parts, _ = EstimateUpload(size, partSize) {
+		if !b.config.AutoPartSize {
+			return 0, errors.Errorf("upload of %s needs %d parts of %d bytes, more than the %d parts allowed by OSS, "+
+				"enable auto_part_size or use larger parts", name, parts, partSize, b.maxParts)
+		}
+		partSize *= 2
+	}
+	if partSize != b.partSize {
+		level.Info(b.logger).Log("msg", "increased part size to stay within the part limit", "object", name, "size", size, "part_size", partSize)
+	}
+	return partSize, nil
+}
+
+// upload writes the object with the given logical name and returns the number of bytes uploaded. It is the
+// operation of Upload and its variants, limited by the request timeout.
+func (b *Bucket) upload(ctx context.Context, name string, r io.Reader, p *uploadParams) (int64, error) {
+	b = b.operation("upload")
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	start := time.Now()
+	n, err := b.uploadObject(ctx, b.physical(name), r, p)
+	b.observe("upload", name, start, err, "bytes", n)
+	return n, err
+}
+
+func (b *Bucket) uploadObject(ctx context.Context, name string, r io.Reader, p *uploadParams) (int64, error) {
+	defer b.forget(name)
+	if err := b.checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if p.checkETag {
+		if err := b.checkETag(name, strings.Trim(p.ifMatch, `"`)); err != nil {
+			return 0, err
+		}
+	}
+
+	chunksnum, lastslice, err := calculateChunks(name, r, b.partSize)
+	if err != nil {
+		return 0, err
+	}
+	if chunksnum < 0 {
+		return b.uploadStream(ctx, name, r, p)
+	}
+	size := int64(chunksnum)*b.partSize + lastslice
+	partSize, err := b.partSizeFor(name, size)
+	if err != nil {
+		return 0, err
+	}
+	if b.config.SHA256Metadata {
+		if p.sha256, err = seekableSHA256(r.(io.ReadSeeker)); err != nil {
+			return 0, errors.Wrap(err, "failed to hash upload source")
+		}
+	}
 
-	ncloser := ioutil.NopCloser(r)
-	switch chunksnum {
-	case 0:
-		if err := b.bucket.PutObject(name, ncloser); err != nil {
-			return errors.Wrap(err, "failed to upload oss object")
+	var crc uint64
+	switch {
+	case size < b.multipartThreshold():
+		if b.config.EnableCRCValidation {
+			if crc, err = seekableCRC64(r.(io.ReadSeeker), size); err != nil {
+				return 0, errors.Wrap(err, "failed to checksum upload source")
+			}
+		}
+		src := &sourceReader{ReadSeeker: r.(io.ReadSeeker)}
+		err := b.retryUpload(ctx, src, func() error {
+			if err := b.putObject(ctx, name, ioutil.NopCloser(src), p); err != nil {
+				return src.wrap(err, "failed to upload oss object")
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
 		}
 	default:
+		if f, ok := b.resumableSource(r, p); ok {
+			if crc, err = b.uploadResumable(ctx, name, f, size, partSize, p); err != nil {
+				return 0, err
+			}
+			break
+		}
 		{
-			init, err := b.bucket.InitiateMultipartUpload(name)
+			mu, err := b.initiateMultipartUpload(name, p)
 			if err != nil {
-				return errors.Wrap(err, "failed to initiate multi-part upload")
-			}
-			chunk := 0
-			uploadEveryPart := func(everypartsize int64, cnk int) (alioss.UploadPart, error) {
-				prt, err := b.bucket.UploadPart(init, ncloser, everypartsize, cnk)
-				if err != nil {
-					if err := b.bucket.AbortMultipartUpload(init); err != nil {
-						return prt, errors.Wrap(err, "failed to abort multi-part upload")
-					}
-
-					return prt, errors.Wrap(err, "failed to upload multi-part chunk")
-				}
-				return prt, nil
+				return 0, errors.Wrap(err, "failed to initiate multi-part upload")
 			}
 			var parts []alioss.UploadPart
-			for ; chunk < chunksnum; chunk++ {
-				part, err := uploadEveryPart(PartSize, chunk+1)
-				if err != nil {
-					return errors.Wrap(err, "failed to upload every part")
-				}
-				parts = append(parts, part)
-			}
-			if lastslice != 0 {
-				part, err := uploadEveryPart(lastslice, chunksnum+1)
-				if err != nil {
-					return errors.Wrap(err, "failed to upload the last chunk")
-				}
-				parts = append(parts, part)
+			parts, crc, err = b.uploadParts(ctx, mu, r.(io.ReadSeeker), size, partSize)
+			if err != nil {
+				return 0, b.abortFailedUpload(mu, err, "failed to upload every part")
 			}
-			if _, err := b.bucket.CompleteMultipartUpload(init, parts); err != nil {
-				return errors.Wrap(err, "failed to set multi-part upload completive")
+			if err := b.completeMultipartUpload(mu.init, parts, p); err != nil {
+				return 0, errors.Wrap(err, "failed to set multi-part upload completive")
 			}
 		}
 	}
-	return nil
+	return size, b.verifyCRC64(ctx, name, crc)
+}
+
+// sourceReader records the first error returned by an upload source, so that failing to read the source
+// is reported as such rather than as the OSS request error it causes.
+type sourceReader struct {
+	io.ReadSeeker
+	err error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.ReadSeeker.Read(p)
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// wrap returns the source error if reading the source failed, and otherwise err.
+func (s *sourceReader) wrap(err error, msg string) error {
+	if s.err != nil {
+		return errors.Wrap(s.err, "failed to read upload source")
+	}
+	return errors.Wrap(err, msg)
+}
+
+// uploadStream uploads the contents of a reader of unknown size. The reader is consumed one part at a
+// time, so only a single part is held in memory and data shorter than a part is sent with one PutObject.
+func (b *Bucket) uploadStream(ctx context.Context, name string, r io.Reader, p *uploadParams) (int64, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(r, b.partSize))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read upload source")
+	}
+	var h hash.Hash
+	if b.config.SHA256Metadata {
+		h = sha256.New()
+		_, _ = h.Write(buf)
+	}
+	crc := crc64.New(crc64Table)
+	_, _ = crc.Write(buf)
+	if int64(len(buf)) < b.partSize && int64(len(buf)) < b.multipartThreshold() {
+		if h != nil {
+			p.sha256 = hex.EncodeToString(h.Sum(nil))
+		}
+		body := bytes.NewReader(buf)
+		if err := b.retryUpload(ctx, body, func() error { return b.putObject(ctx, name, body, p) }); err != nil {
+			return 0, errors.Wrap(err, "failed to upload oss object")
+		}
+		return int64(len(buf)), b.verifyCRC64(ctx, name, crc.Sum64())
+	}
+
+	mu, err := b.initiateMultipartUpload(name, p)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to initiate multi-part upload")
+	}
+	abort := func(err error, msg string) error { return b.abortFailedUpload(mu, err, msg) }
+
+	var (
+		parts []alioss.UploadPart
+		size  int64
+	)
+	for cnk := 1; len(buf) > 0; cnk++ {
+		if err := ctx.Err(); err != nil {
+			return 0, abort(err, "multi-part upload cancelled")
+		}
+		if cnk > b.maxParts {
+			err := errors.Errorf("streamed upload exceeds the %d parts allowed by OSS with parts of %d bytes and "+
+				"cannot be restarted with larger parts as the source is not seekable, use larger parts", b.maxParts, b.partSize)
+			return 0, abort(err, "failed to upload multi-part chunk")
+		}
+		var part alioss.UploadPart
+		body := bytes.NewReader(buf)
+		err := b.retryUpload(ctx, body, func() (err error) {
+			part, err = b.uploadPart(ctx, mu, body, int64(len(buf)), cnk)
+			return err
+		})
+		if err != nil {
+			return 0, abort(err, "failed to upload multi-part chunk")
+		}
+		parts = append(parts, part)
+		size += int64(len(buf))
+
+		if buf, err = ioutil.ReadAll(io.LimitReader(r, b.partSize)); err != nil {
+			return 0, abort(err, "failed to read upload source")
+		}
+		if h != nil {
+			_, _ = h.Write(buf)
+		}
+		_, _ = crc.Write(buf)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, abort(err, "multi-part upload cancelled")
+	}
+	if err := b.completeMultipartUpload(mu.init, parts, p); err != nil {
+		return 0, errors.Wrap(err, "failed to set multi-part upload completive")
+	}
+	// The hash is only known once the whole stream is read, after the upload was initiated.
+	if h != nil {
+		// Replacing the metadata rewrites the object, which must be typed, encrypted and classified again,
+		// and given its user metadata again.
+		opts := append(metaOptions(p.meta), alioss.Meta(sha256MetaKey, hex.EncodeToString(h.Sum(nil))))
+		opts = append(opts, b.createOptions(name, p)...)
+		if err := b.bucket.SetObjectMeta(name, opts...); err != nil {
+			return size, errors.Wrap(err, "failed to set sha256 metadata")
+		}
+	}
+	return size, b.verifyCRC64(ctx, name, crc.Sum64())
 }
 
 // Delete removes the object with the given name.
 func (b *Bucket) Delete(ctx context.Context, name string) error {
-	if err := b.bucket.DeleteObject(name); err != nil {
-		return errors.Wrap(err, "delete oss object")
+	b = b.operation("delete")
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	err = b.deleteObject(ctx, name)
+	b.observe("delete", name, start, err)
+	return err
+}
+
+func (b *Bucket) deleteObject(ctx context.Context, name string) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := b.checkProtected(ctx, name); err != nil {
+		b.audit(OpDelete, name, 0, start, err)
+		return err
+	}
+	err := b.retry(ctx, func() error { return b.bucket.DeleteObject(name) })
+	b.forget(name)
+	if err != nil {
+		err = withClockSkewHint(errors.Wrap(err, "delete oss object"))
+	}
+	b.audit(OpDelete, name, 0, start, err)
+	return err
+}
+
+// checkProtected returns an error if the object carries the configured protected tag. The options select
+// the version of the object to check, the latest one by default.
+func (b *Bucket) checkProtected(ctx context.Context, name string, opts ...alioss.Option) error {
+	if b.config.ProtectedTagKey == "" {
+		return nil
+	}
+	var tagging alioss.GetObjectTaggingResult
+	err := b.retry(ctx, func() (err error) {
+		tagging, err = b.bucket.GetObjectTagging(name, opts...)
+		return err
+	})
+	if err != nil {
+		// Deleting a missing object is not an error.
+		if b.IsObjNotFoundErr(err) {
+			return nil
+		}
+		return withClockSkewHint(errors.Wrapf(err, "get tags of oss object %s", name))
+	}
+	for _, tag := range tagging.Tags {
+		if tag.Key == b.config.ProtectedTagKey {
+			return errors.Errorf("oss object %s is protected by tag %s=%s and cannot be deleted", name, tag.Key, tag.Value)
+		}
 	}
 	return nil
 }
 
-// NewBucket returns a new Bucket using the provided oss config values.
-func NewBucket(logger log.Logger, conf []byte, component string) (*Bucket, error) {
+// NewBucket returns a new Bucket using the provided oss config values. Without any credentials in the config,
+// they are read from the ALIYUN_ACCESS_KEY_ID, ALIYUN_ACCESS_KEY_SECRET and ALIYUN_SECURITY_TOKEN environment
+// variables.
+func NewBucket(logger log.Logger, conf []byte, component string, opts ...Option) (*Bucket, error) {
 	var config Config
 	if err := yaml.Unmarshal(conf, &config); err != nil {
 		return nil, errors.Wrap(err, "parse aliyun oss config file failed")
 	}
 
-	if config.Endpoint == "" || config.Bucket == "" || config.AccessKeyID == "" || config.AccessKeySecret == "" {
+	switch config.IterDirectoryKeys {
+	case "", IterDirectoryKeysPrefix, IterDirectoryKeysSkip:
+	default:
+		return nil, errors.Errorf("unsupported aliyun oss iter_directory_keys %q", config.IterDirectoryKeys)
+	}
+	if strings.HasPrefix(config.Prefix, objstore.DirDelim) {
+		return nil, errors.Errorf("aliyun oss prefix %q must not start with %s", config.Prefix, objstore.DirDelim)
+	}
+	if config.ReadRepairAttempts < 0 {
+		return nil, errors.Errorf("invalid aliyun oss read_repair_attempts %d", config.ReadRepairAttempts)
+	}
+	if err := config.ParallelRange.validate(); err != nil {
+		return nil, err
+	}
+	switch config.StorageClass {
+	case "", StorageClassStandard, StorageClassIA, StorageClassArchive, StorageClassColdArchive:
+	default:
+		return nil, errors.Errorf("unsupported aliyun oss storage_class %q", config.StorageClass)
+	}
+	if config.ObjectACL != "" {
+		if err := validateObjectACL(config.ObjectACL); err != nil {
+			return nil, errors.Wrap(err, "invalid aliyun oss object_acl")
+		}
+	}
+	if err := config.SSEConfig.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.HTTPConfig.validate(); err != nil {
+		return nil, err
+	}
+	if config.EnableResumableUpload {
+		if config.CheckpointDir == "" {
+			return nil, errors.New("aliyun oss checkpoint_dir must be set with enable_resumable_upload")
+		}
+		if err := os.MkdirAll(config.CheckpointDir, 0750); err != nil {
+			return nil, errors.Wrap(err, "create aliyun oss checkpoint_dir")
+		}
+	}
+	if config.ReadOnly && config.CreateBucket {
+		return nil, errors.New("aliyun oss create_bucket cannot be combined with read_only")
+	}
+	if config.UploadConcurrency < 0 {
+		return nil, errors.Errorf("invalid aliyun oss upload_concurrency %d", config.UploadConcurrency)
+	}
+	if n := config.ListObjectsMaxKeys; n < 0 || n > MaxListObjectsMaxKeys {
+		return nil, errors.Errorf("aliyun oss list_objects_max_keys %d out of the range 1-%d accepted by OSS", n, MaxListObjectsMaxKeys)
+	}
+	if config.MaxListBufferObjects < 0 {
+		return nil, errors.Errorf("invalid aliyun oss max_list_buffer_objects %d", config.MaxListBufferObjects)
+	}
+	if config.PartSize != 0 && config.PartSize < MinPartSize {
+		return nil, errors.Errorf("aliyun oss part_size %d below the minimum of %d accepted by OSS", config.PartSize, MinPartSize)
+	}
+	if config.MultipartThreshold > MaxPutObjectSize {
+		return nil, errors.Errorf("aliyun oss multipart_threshold %d above the maximum of %d accepted by OSS for a single PutObject",
+			config.MultipartThreshold, MaxPutObjectSize)
+	}
+	if config.MaxConcurrentRequests < 0 {
+		return nil, errors.Errorf("invalid aliyun oss max_concurrent_requests %d", config.MaxConcurrentRequests)
+	}
+	if config.UploadBandwidthLimit < 0 {
+		return nil, errors.Errorf("invalid aliyun oss upload_bandwidth_limit %d", config.UploadBandwidthLimit)
+	}
+	if config.DownloadBandwidthLimit < 0 {
+		return nil, errors.Errorf("invalid aliyun oss download_bandwidth_limit %d", config.DownloadBandwidthLimit)
+	}
+	if l := config.TrafficLimitBytesPerSec; l != 0 && (l < MinTrafficLimitBytesPerSec || l > MaxTrafficLimitBytesPerSec) {
+		return nil, errors.Errorf("aliyun oss traffic_limit_bytes_per_sec %d out of the range %d-%d accepted by OSS",
+			l, MinTrafficLimitBytesPerSec, MaxTrafficLimitBytesPerSec)
+	}
+
+	bkt := &Bucket{
+		logger:          logger,
+		name:            config.Bucket,
+		config:          config,
+		component:       component,
+		partSize:        PartSize,
+		maxParts:        MaxParts,
+		maxCopySize:     MaxCopyObjectSize,
+		metadataURL:     ecsRAMRoleCredentialsURL,
+		metrics:         newMetrics(config.Bucket),
+		retained:        newRetainedUploads(),
+		uploadLimiter:   newBandwidthLimiter(config.UploadBandwidthLimit),
+		downloadLimiter: newBandwidthLimiter(config.DownloadBandwidthLimit),
+	}
+	if config.PartSize != 0 {
+		bkt.partSize = int64(config.PartSize)
+	}
+	if config.MaxConcurrentRequests > 0 {
+		bkt.sem = semaphore.NewWeighted(int64(config.MaxConcurrentRequests))
+	}
+	for _, opt := range opts {
+		opt.apply(bkt)
+	}
+	bkt.keys = withPrefix(bkt.keys, config.Prefix)
+	// Credentials set in the config, in any way, take precedence over the environment.
+	if config.AccessKeyID == "" && config.AccessKeySecret == "" && config.CredentialsFile == "" && config.RAMRole == "" &&
+		bkt.credentialsProvider == nil {
+		config.AccessKeyID = os.Getenv(envAccessKeyID)
+		config.AccessKeySecret = os.Getenv(envAccessKeySecret)
+		if config.SecurityToken == "" {
+			config.SecurityToken = os.Getenv(envSecurityToken)
+		}
+		bkt.config = config
+	}
+	if config.HTTPConfig.InsecureSkipVerify {
+		if config.HTTPConfig.TLSConfig.CAFile != "" {
+			level.Warn(logger).Log("msg", "ignoring aliyun oss http_config.insecure_skip_verify, certificates are verified against http_config.tls_config.ca_file")
+		} else {
+			level.Warn(logger).Log("msg", "TLS certificate verification of the aliyun oss endpoint is disabled by http_config.insecure_skip_verify")
+		}
+	}
+	// The transport configured by http_config, if any, also fetches RAM role credentials.
+	t, err := newHTTPTransport(config.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	var configured http.RoundTripper
+	if t != nil {
+		configured = t
+	}
+	if bkt.credentialsProvider == nil {
+		switch {
+		case config.CredentialsFile != "":
+			bkt.credentialsProvider = fileCredentialsProvider(config.CredentialsFile)
+		case config.RAMRole != "" && config.AccessKeyID == "" && config.AccessKeySecret == "":
+			bkt.credentialsProvider = ecsRAMRoleCredentialsProvider(bkt.metadataURL, config.RAMRole, configured)
+		}
+	}
+	staticCredentials := config.AccessKeyID != "" && config.AccessKeySecret != ""
+	if config.Endpoint == "" || config.Bucket == "" || (!staticCredentials && bkt.credentialsProvider == nil) {
 		return nil, errors.New("aliyun oss endpoint or bucket or access_key_id or access_key_secret " +
 			"is not present in config file")
 	}
 
-	client, err := alioss.New(config.Endpoint, config.AccessKeyID, config.AccessKeySecret)
+	if config.CName {
+		if config.UseInternalEndpoint {
+			return nil, errors.New("aliyun oss cname and use_internal_endpoint cannot be used together")
+		}
+		if err := validateCNameEndpoint(config.Endpoint, config.Bucket); err != nil {
+			return nil, err
+		}
+	}
+	if config.UseInternalEndpoint {
+		if internal, ok := internalEndpoint(config.Endpoint); ok {
+			config.Endpoint = internal
+		} else {
+			level.Warn(logger).Log("msg", "aliyun oss endpoint has no internal form, using it as it is", "endpoint", config.Endpoint)
+		}
+	}
+	if config.Endpoint, err = normalizeEndpoint(config.Endpoint, config.Insecure); err != nil {
+		return nil, err
+	}
+	bkt.config.Endpoint = config.Endpoint
+
+	if bkt.transport == nil {
+		bkt.transport = configured
+	}
+	if bkt.transport == nil && (config.ListTimeout > 0 || config.RequestTimeout > 0 || bkt.limiter != nil || config.EnableResumableUpload) {
+		// The SDK's transport cannot be wrapped, an equivalent one is used instead.
+		if bkt.transport, err = httpTransport(config.HTTPConfig); err != nil {
+			return nil, err
+		}
+	}
+	bkt.transport = bkt.wrapTransport(bkt.transport)
+	clientOpts := clientOptions(config, component, bkt.transport)
+	if bkt.credentialsProvider != nil {
+		creds, err := newRefreshingCredentials(logger, bkt.credentialsProvider,
+			time.Duration(config.CredentialsRefreshInterval), time.Duration(config.CredentialsExpirySkew))
+		if err != nil {
+			return nil, err
+		}
+		bkt.credentials = creds
+		clientOpts = append(clientOpts, alioss.SetCredentialsProvider(creds))
+	}
+	client, err := alioss.New(config.Endpoint, config.AccessKeyID, config.AccessKeySecret, clientOpts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "create aliyun oss client failed")
 	}
@@ -159,44 +1024,238 @@ func NewBucket(logger log.Logger, conf []byte, component string) (*Bucket, error
 	if err != nil {
 		return nil, errors.Wrapf(err, "use aliyun oss bucket %s failed", config.Bucket)
 	}
-
-	bkt := &Bucket{
-		logger: logger,
-		client: client,
-		name:   config.Bucket,
-		config: config,
-		bucket: bk,
+	bkt.client = client
+	bkt.bucket = bk
+	if err := bkt.newOperationClients(); err != nil {
+		return nil, err
+	}
+	if config.SmallObjectThreshold > 0 {
+		cacheSize := config.SmallObjectCacheSize
+		if cacheSize == 0 {
+			cacheSize = defaultSmallObjectCacheSize
+		}
+		if cacheSize < config.SmallObjectThreshold {
+			return nil, errors.New("aliyun oss small_object_cache_size must not be less than small_object_threshold")
+		}
+		if bkt.smallObjects, err = newObjectCache(cacheSize); err != nil {
+			return nil, errors.Wrap(err, "create small object cache")
+		}
+	}
+	if config.KeyAllowRegex != "" {
+		if bkt.allowKeys, err = regexp.Compile(config.KeyAllowRegex); err != nil {
+			return nil, errors.Wrap(err, "parse aliyun oss key_allow_regex failed")
+		}
+	}
+	if config.KeyDenyRegex != "" {
+		if bkt.denyKeys, err = regexp.Compile(config.KeyDenyRegex); err != nil {
+			return nil, errors.Wrap(err, "parse aliyun oss key_deny_regex failed")
+		}
+	}
+	if bkt.credentials != nil {
+		bkt.credentials.start()
+	}
+	if config.CheckBucketExists {
+		if err := bkt.EnsureBucket(context.Background()); err != nil {
+			_ = bkt.Close()
+			return nil, err
+		}
+	}
+	// The metrics are registered last, so that failing to create the Bucket leaves the registerer as it
+	// was and can be retried.
+	if bkt.registerer != nil {
+		if err := bkt.metrics.register(bkt.registerer); err != nil {
+			_ = bkt.Close()
+			return nil, err
+		}
 	}
 	return bkt, nil
 }
 
-// Iter calls f for each entry in the given directory (not recursive). The argument to f is the full
-// object name including the prefix of the inspected directory.
-func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error) error {
-	if dir != "" {
-		dir = strings.TrimSuffix(dir, objstore.DirDelim) + objstore.DirDelim
+// wrapTransport returns rt wrapped to enforce the list timeout, request timeout and concurrency limit
+// configured for the Bucket.
+func (b *Bucket) wrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if b.config.ListTimeout > 0 {
+		rt = listTimeoutTransport{bucket: b.config.Bucket, timeout: time.Duration(b.config.ListTimeout), next: rt}
+	}
+	if b.config.RequestTimeout > 0 {
+		rt = requestTimeoutTransport{timeout: time.Duration(b.config.RequestTimeout), next: rt}
+	}
+	if b.limiter != nil {
+		rt = limitedTransport{limiter: b.limiter, next: rt}
+	}
+	return rt
+}
+
+// newClient returns the OSS bucket of a client like the one of the Bucket, with the same credentials,
+// sending its requests through rt.
+func (b *Bucket) newClient(rt http.RoundTripper) (*alioss.Bucket, error) {
+	opts := append(clientOptions(b.config, b.component, rt), alioss.SetCredentialsProvider(b.client.Config.CredentialsProvider))
+	client, err := alioss.New(b.config.Endpoint, b.config.AccessKeyID, b.config.AccessKeySecret, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "create aliyun oss client failed")
+	}
+	bk, err := client.Bucket(b.bucket.BucketName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "use aliyun oss bucket %s failed", b.bucket.BucketName)
+	}
+	return bk, nil
+}
+
+// clientOptions returns the options of the OSS client for the given config, sending requests through the
+// given transport unless it is nil. The User-Agent of requests names the Thanos component and version.
+func clientOptions(config Config, component string, transport http.RoundTripper) []alioss.ClientOption {
+	opts := []alioss.ClientOption{
+		alioss.UserAgent(fmt.Sprintf("thanos-%s/%s (%s)", component, version.Version, runtime.Version())),
+	}
+	if config.SecurityToken != "" {
+		opts = append(opts, alioss.SecurityToken(config.SecurityToken))
+	}
+	if config.CName {
+		opts = append(opts, alioss.UseCname(true))
+	}
+	if transport != nil {
+		opts = append(opts, alioss.HTTPClient(&http.Client{Transport: transport}))
+	}
+	return opts
+}
+
+// onlyDirectoryKey reports whether the only object under the directory is the key naming the directory itself.
+func (b *Bucket) onlyDirectoryKey(ctx context.Context, dir string) (bool, error) {
+	var objects alioss.ListObjectsResult
+	err := b.retryList(ctx, func() (err error) {
+		objects, err = b.bucket.ListObjects(alioss.Prefix(dir), alioss.MaxKeys(2))
+		return err
+	})
+	if err != nil {
+		return false, err
 	}
+	return len(objects.Objects) == 1 && objects.Objects[0].Key == dir, nil
+}
+
+// Iter calls f for each entry in the given directory, not recursive unless objstore.WithRecursiveIter is
+// given. The argument to f is the full object name including the prefix of the inspected directory.
+// Entries rejected by the configured key filters are skipped and keys ending with the delimiter are
+// handled as set by iter_directory_keys. An empty dir, or one made of the delimiter only, lists the root
+// of the bucket. OSS has no directories, so a directory that does not exist, even if it is named
+// like an existing object, yields no entries without an error, just like an empty one.
+func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	b = b.operation("iter")
+	start := time.Now()
+	params := objstore.ApplyIterOptions(options...)
+	err := b.iter(ctx, dir, params, func(o alioss.ObjectProperties) error { return f(o.Key) }, f)
+	b.observe("iter", dir, start, err, "recursive", params.Recursive)
+	return err
+}
 
+// IterWithAttributes calls f for each entry in the given directory like IterObjects, passing only the
+// size and last modification time returned by the listing along with the name.
+func (b *Bucket) IterWithAttributes(ctx context.Context, dir string, f func(objstore.IterObjectAttributes) error, options ...objstore.IterOption) error {
+	return b.IterObjects(ctx, dir, func(o ObjectInfo) error {
+		if o.IsDir {
+			return f(objstore.IterObjectAttributes{Name: o.Key})
+		}
+		return f(objstore.IterObjectAttributes{
+			Name:             o.Key,
+			ObjectAttributes: objstore.ObjectAttributes{Size: o.Size, LastModified: o.LastModified},
+		})
+	}, options...)
+}
+
+// iter lists the given directory like Iter, calling onObject for every object, with the key mapped to
+// its logical name, and onDir for every subdirectory. The whole listing is limited by the request timeout.
+func (b *Bucket) iter(ctx context.Context, dir string, params objstore.IterParams, onObject func(alioss.ObjectProperties) error, onDir func(string) error) error {
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	if dir = strings.TrimSuffix(dir, objstore.DirDelim); dir != "" {
+		dir += objstore.DirDelim
+	}
+	dir = b.physical(dir)
+	maxKeys := b.config.ListObjectsMaxKeys
+	if maxKeys == 0 {
+		maxKeys = MaxListObjectsMaxKeys
+	}
+	if n := b.config.MaxListBufferObjects; n > 0 && n < maxKeys {
+		maxKeys = n
+	}
+	opts := []alioss.Option{alioss.Prefix(dir), alioss.MaxKeys(maxKeys)}
+	if !params.Recursive {
+		opts = append(opts, alioss.Delimiter(objstore.DirDelim))
+	}
 	marker := alioss.Marker("")
+	// last is the greatest entry of the previous page, objects and directories alike.
+	var last string
 	for {
 		if err := ctx.Err(); err != nil {
 			return errors.Wrap(err, "context closed while iterating bucket")
 		}
-		objects, err := b.bucket.ListObjects(alioss.Prefix(dir), alioss.Delimiter(objstore.DirDelim), marker)
+		var objects alioss.ListObjectsResult
+		// Listing holds a slot per page only, so that f may use the Bucket without exceeding the limit.
+		release, err := b.acquire(ctx)
 		if err != nil {
-			return errors.Wrap(err, "listing aliyun oss bucket failed")
+			return errors.Wrap(err, "context closed while iterating bucket")
+		}
+		err = b.retryList(ctx, func() (err error) {
+			objects, err = b.bucket.ListObjects(append(opts, marker)...)
+			return err
+		})
+		release()
+		if err != nil {
+			return withClockSkewHint(errors.Wrap(err, "listing aliyun oss bucket failed"))
+		}
+		if n, max := len(objects.Objects)+len(objects.CommonPrefixes), b.config.MaxListBufferObjects; max > 0 && n > max {
+			return errors.Errorf("listing aliyun oss bucket failed: page of %d entries exceeds max_list_buffer_objects %d", n, max)
+		}
+		repeated := last
+		if !b.config.DedupListingKeys {
+			repeated = ""
+		}
+		last = lastEntry(objects)
+		// The SDK lists with encoding-type url and decodes the keys and markers, so keys OSS cannot put in
+		// XML as they are list fine and NextMarker is sent back as it is. Truncated pages without one, as
+		// returned by some OSS compatible endpoints, continue after their last entry.
+		next := objects.NextMarker
+		if next == "" {
+			next = last
+		}
+		if objects.IsTruncated && next == "" {
+			return errors.New("listing aliyun oss bucket failed: truncated page without entries or next marker")
 		}
-		marker = alioss.Marker(objects.NextMarker)
+		marker = alioss.Marker(next)
 
 		for _, object := range objects.Objects {
-			if err := f(object.Key); err != nil {
-				return errors.Wrapf(err, "callback func invoke for object %s failed ", object.Key)
+			// A key naming the directory itself is not an entry of it.
+			if object.Key == dir || object.Key == repeated || !b.keyAllowed(object.Key) {
+				continue
+			}
+			name, ok := b.logical(object.Key)
+			if !ok {
+				continue
+			}
+			object.Key = name
+			if err := onObject(object); err != nil {
+				return errors.Wrapf(err, "callback func invoke for object %s failed ", name)
 			}
 		}
 
 		for _, object := range objects.CommonPrefixes {
-			if err := f(object); err != nil {
-				return errors.Wrapf(err, "callback func invoke for directory %s failed", object)
+			if object == repeated || !b.keyAllowed(object) {
+				continue
+			}
+			if b.config.IterDirectoryKeys == IterDirectoryKeysSkip {
+				only, err := b.onlyDirectoryKey(ctx, object)
+				if err != nil {
+					return errors.Wrapf(err, "listing aliyun oss directory %s failed", object)
+				}
+				if only {
+					continue
+				}
+			}
+			name, ok := b.logical(object)
+			if !ok {
+				continue
+			}
+			if err := onDir(name); err != nil {
+				return errors.Wrapf(err, "callback func invoke for directory %s failed", name)
 			}
 		}
 		if !objects.IsTruncated {
@@ -207,6 +1266,47 @@ func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error) err
 	return nil
 }
 
+// lastEntry returns the greatest key or common prefix of the listing page.
+func lastEntry(page alioss.ListObjectsResult) string {
+	var last string
+	if n := len(page.Objects); n > 0 {
+		last = page.Objects[n-1].Key
+	}
+	if n := len(page.CommonPrefixes); n > 0 && page.CommonPrefixes[n-1] > last {
+		last = page.CommonPrefixes[n-1]
+	}
+	return last
+}
+
+// Validate checks that the bucket is usable end to end by uploading a small probe object, reading it
+// back and deleting it. The returned error names the step that failed.
+func (b *Bucket) Validate(ctx context.Context) error {
+	name := fmt.Sprintf("thanos-validate-%x", rand.Int63())
+	probe := "thanos oss bucket validation probe"
+
+	if err := b.Upload(ctx, name, strings.NewReader(probe)); err != nil {
+		return errors.Wrapf(err, "validate: upload probe object %s", name)
+	}
+
+	rc, err := b.Get(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "validate: get probe object %s", name)
+	}
+	data, err := ioutil.ReadAll(rc)
+	runutil.CloseWithLogOnErr(b.logger, rc, "oss validate probe reader")
+	if err != nil {
+		return errors.Wrapf(err, "validate: read probe object %s", name)
+	}
+	if string(data) != probe {
+		return errors.Errorf("validate: probe object %s read back %d bytes not matching the %d bytes uploaded", name, len(data), len(probe))
+	}
+
+	if err := b.Delete(ctx, name); err != nil {
+		return errors.Wrapf(err, "validate: delete probe object %s", name)
+	}
+	return nil
+}
+
 func (b *Bucket) Name() string {
 	return b.name
 }
@@ -259,21 +1359,17 @@ func NewTestBucketFromConfig(t testing.TB, c Config, reuseBucket bool) (objstore
 	}, nil
 }
 
-func (b *Bucket) Close() error { return nil }
+// Close stops refreshing the credentials of the bucket, if any.
+func (b *Bucket) Close() error {
+	if b.credentials != nil {
+		b.credentials.stop()
+	}
+	return nil
+}
 
-func (b *Bucket) setRange(start, end int64, name string) (alioss.Option, error) {
+func setRange(start, end, size int64) (alioss.Option, error) {
 	var opt alioss.Option
 	if 0 <= start && start <= end {
-		header, err := b.bucket.GetObjectMeta(name)
-		if err != nil {
-			return nil, err
-		}
-
-		size, err := strconv.ParseInt(header["Content-Length"][0], 10, 0)
-		if err != nil {
-			return nil, err
-		}
-
 		if end > size {
 			end = size - 1
 		}
@@ -285,21 +1381,131 @@ func (b *Bucket) setRange(start, end int64, name string) (alioss.Option, error)
 	return opt, nil
 }
 
+// objectMeta returns the size and ETag of the object.
+func (b *Bucket) objectMeta(name string) (int64, string, error) {
+	header, err := b.bucket.GetObjectMeta(name)
+	if err != nil {
+		return 0, "", err
+	}
+
+	size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 0)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, header.Get("ETag"), nil
+}
+
+// createOptions returns the SDK options of requests creating objects, setting their content type,
+// encryption, storage class and ACL.
+func (b *Bucket) createOptions(name string, p *uploadParams) []alioss.Option {
+	typ := p.contentType
+	if typ == "" {
+		typ = contentType(name)
+	}
+	opts := append([]alioss.Option{alioss.ContentType(typ)}, b.config.SSEConfig.options()...)
+	if b.config.StorageClass != "" {
+		opts = append(opts, alioss.ObjectStorageClass(alioss.StorageClassType(b.config.StorageClass)))
+	}
+	if b.config.ObjectACL != "" {
+		opts = append(opts, alioss.ObjectACL(alioss.ACLType(b.config.ObjectACL)))
+	}
+	return opts
+}
+
+// readOptions returns the SDK options of requests reading object content.
+func (b *Bucket) readOptions() []alioss.Option {
+	opts := b.transferOptions()
+	if b.config.IdentityEncoding {
+		opts = append(opts, alioss.AcceptEncoding("identity"))
+	}
+	return opts
+}
+
+// transferOptions returns the SDK options of requests transferring object content in either direction.
+func (b *Bucket) transferOptions() []alioss.Option {
+	if b.config.TrafficLimitBytesPerSec > 0 {
+		// OSS takes the limit in bits per second.
+		return []alioss.Option{alioss.TrafficLimitHeader(b.config.TrafficLimitBytesPerSec * 8)}
+	}
+	return nil
+}
+
 func (b *Bucket) getRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
 	if len(name) == 0 {
 		return nil, errors.New("given object name should not empty")
 	}
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if off < 0 {
+		return nil, errors.Errorf("invalid range of oss object %s: negative offset %d", name, off)
+	}
+	if length < -1 {
+		return nil, errors.Errorf("invalid range of oss object %s: length %d is neither -1 nor at least 0", name, length)
+	}
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
 
-	var opts []alioss.Option
+	rc, err := b.openRange(ctx, name, off, length)
+	if err != nil {
+		return nil, err
+	}
+	return b.downloadReader(ctx, rc), nil
+}
+
+func (b *Bucket) openRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	opts := b.readOptions()
+	// Ranges to the end of the object have no end to clamp to its size, so they never need it looked up.
+	if length == -1 && off != 0 {
+		return b.getDirectRange(ctx, name, off, length, opts)
+	}
+	if length != -1 && !b.config.StrictRangeValidation && b.smallObjects == nil && !b.config.ParallelRange.splits(length) {
+		return b.getDirectRange(ctx, name, off, length, opts)
+	}
 	if length != -1 {
-		opt, err := b.setRange(off, off+length-1, name)
+		var (
+			size int64
+			etag string
+		)
+		err := b.retry(ctx, func() (err error) {
+			size, etag, err = b.objectMeta(name)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
+		if b.smallObjects != nil && size <= b.config.SmallObjectThreshold {
+			return b.getSmallObjectRange(ctx, name, etag, off, length)
+		}
+
+		opt, err := setRange(off, off+length-1, size)
+		if err != nil {
+			return nil, err
+		}
+		end := off + length - 1
+		if end >= size {
+			end = size - 1
+		}
+		if off < size && b.config.ParallelRange.splits(end-off+1) {
+			return b.getParallelRange(ctx, name, off, end, opts), nil
+		}
 		opts = append(opts, opt)
 	}
 
-	resp, err := b.bucket.GetObject(name, opts...)
+	if length == -1 && b.config.ReadRepairAttempts > 0 {
+		return b.getRepaired(ctx, name, opts)
+	}
+
+	var resp io.ReadCloser
+	err := b.retry(ctx, func() (err error) {
+		resp, err = b.bucket.GetObject(name, opts...)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -307,35 +1513,242 @@ func (b *Bucket) getRange(ctx context.Context, name string, off, length int64) (
 	return resp, nil
 }
 
-// Get returns a reader for the given object name.
+// getDirectRange issues the ranged GET without looking up the size of the object first. A length of -1
+// requests the range from off to the end of the object. OSS ignores ranges ending or starting past the
+// end of the object and returns the whole object instead, in which case the range is requested again,
+// clamped to the size of the object reported by that response. Ranges starting past the end of the
+// object are empty.
+func (b *Bucket) getDirectRange(ctx context.Context, name string, off, length int64, opts []alioss.Option) (io.ReadCloser, error) {
+	rangeOpt := alioss.Range(off, off+length-1)
+	if length == -1 {
+		rangeOpt = alioss.NormalizedRange(fmt.Sprintf("%d-", off))
+	}
+
+	var res *alioss.GetObjectResult
+	err := b.retry(ctx, func() (err error) {
+		res, err = b.bucket.DoGetObject(&alioss.GetObjectRequest{ObjectKey: name}, append(opts, rangeOpt))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Response.StatusCode == http.StatusPartialContent {
+		return res.Response, nil
+	}
+	runutil.CloseWithLogOnErr(b.logger, res.Response, "oss unranged object reader")
+
+	size, err := strconv.ParseInt(res.Response.Headers.Get(alioss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse Content-Length header")
+	}
+	if off >= size {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	var rc io.ReadCloser
+	err = b.retry(ctx, func() (err error) {
+		rc, err = b.bucket.GetObject(name, append(opts, alioss.Range(off, size-1))...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// getSmallObjectRange serves the range from the whole object, which is fetched once and cached. For small
+// objects a full GET is cheaper than issuing ranged reads.
+func (b *Bucket) getSmallObjectRange(ctx context.Context, name, etag string, off, length int64) (io.ReadCloser, error) {
+	if off < 0 || length <= 0 {
+		return nil, errors.Errorf("Invalid range specified: start=%d end=%d", off, off+length-1)
+	}
+
+	data, ok := b.smallObjects.get(name, etag)
+	if !ok {
+		err := b.retry(ctx, func() error {
+			rc, err := b.bucket.GetObject(name, b.readOptions()...)
+			if err != nil {
+				return err
+			}
+			defer runutil.CloseWithLogOnErr(b.logger, rc, "oss small object reader")
+
+			if data, err = ioutil.ReadAll(rc); err != nil {
+				return errors.Wrap(err, "read small object")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		b.smallObjects.set(name, etag, data)
+	}
+
+	if off > int64(len(data)) {
+		off = int64(len(data))
+	}
+	end := off + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return ioutil.NopCloser(bytes.NewReader(data[off:end])), nil
+}
+
+// Get returns a reader for the given object name. Once ctx is done, reading fails with its error.
 func (b *Bucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
-	return b.getRange(ctx, name, 0, -1)
+	b = b.operation("get")
+	ctx, cancel := b.requestContext(ctx)
+	release, err := b.acquire(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	start := time.Now()
+	rc, err := b.getRange(ctx, name, 0, -1)
+	b.observe("get", name, start, err)
+	if err != nil {
+		release()
+		cancel()
+		return nil, withClockSkewHint(err)
+	}
+	return b.releaseOnClose(b.cancelOnClose(rc, cancel), release), nil
 }
 
+// GetRange returns a reader for length bytes of the object starting at off, or for the bytes from off to
+// the end of the object if length is -1. Ranges past the end of the object are empty.
 func (b *Bucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
-	return b.getRange(ctx, name, off, length)
+	b = b.operation("get_range")
+	ctx, cancel := b.requestContext(ctx)
+	release, err := b.acquire(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	start := time.Now()
+	rc, err := b.getRange(ctx, name, off, length)
+	b.observe("get_range", name, start, err, "offset", off, "length", length)
+	if err != nil {
+		release()
+		cancel()
+		return nil, withClockSkewHint(err)
+	}
+	return b.releaseOnClose(b.cancelOnClose(rc, cancel), release), nil
 }
 
 // Exists checks if the given object exists in the bucket.
 func (b *Bucket) Exists(ctx context.Context, name string) (bool, error) {
-	exists, err := b.bucket.IsObjectExist(name)
+	b = b.operation("exists")
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	release, err := b.acquire(ctx)
 	if err != nil {
+		return false, err
+	}
+	defer release()
+	start := time.Now()
+	exists, err := b.exists(ctx, name)
+	b.observe("exists", name, start, err)
+	return exists, err
+}
+
+func (b *Bucket) exists(ctx context.Context, name string) (bool, error) {
+	if _, err := b.headObject(ctx, name); err != nil {
 		if b.IsObjNotFoundErr(err) {
 			return false, nil
 		}
-		return false, errors.Wrap(err, "cloud not check if object exists")
+		return false, withClockSkewHint(errors.Wrap(err, "cloud not check if object exists"))
+	}
+	return true, nil
+}
+
+// Attributes returns the size and last modification time of the object, and its creation time if the
+// uploader stamped it in the creation-time user metadata, see Times. The times are zero if unknown.
+func (b *Bucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	b = b.operation("attributes")
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return objstore.ObjectAttributes{}, err
+	}
+	defer release()
+	start := time.Now()
+	attrs, err := b.attributes(ctx, name)
+	b.observe("attributes", name, start, err)
+	return attrs, err
+}
+
+func (b *Bucket) attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	header, err := b.headObject(ctx, name)
+	if err != nil {
+		return objstore.ObjectAttributes{}, withClockSkewHint(errors.Wrapf(err, "get oss object meta %s", b.physical(name)))
+	}
+	return parseObjectAttributes(header)
+}
+
+// headObject returns the headers of the object as returned by a HEAD request, which carries no content.
+// Key and context errors are returned as they are, errors of the request unwrapped.
+func (b *Bucket) headObject(ctx context.Context, name string) (http.Header, error) {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var header http.Header
+	err := b.retry(ctx, func() (err error) {
+		header, err = b.bucket.GetObjectDetailedMeta(name)
+		return err
+	})
+	return header, err
+}
+
+func parseObjectAttributes(header http.Header) (objstore.ObjectAttributes, error) {
+	var attrs objstore.ObjectAttributes
+	v := header.Get(alioss.HTTPHeaderContentLength)
+	if v == "" {
+		return attrs, errors.New("oss object meta lacks the Content-Length header")
 	}
+	size, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return attrs, errors.Wrap(err, "parse Content-Length header")
+	}
+	attrs.Size = size
 
-	return exists, nil
+	if v := header.Get(alioss.HTTPHeaderLastModified); v != "" {
+		if attrs.LastModified, err = http.ParseTime(v); err != nil {
+			return attrs, errors.Wrap(err, "parse Last-Modified header")
+		}
+	}
+	attrs.Created, err = parseCreationTime(header)
+	return attrs, err
 }
 
 // IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
+// Besides 404 responses, missing objects are recognized by their error code, which some operations
+// return with other status codes.
 func (b *Bucket) IsObjNotFoundErr(err error) bool {
-	switch aliErr := err.(type) {
-	case alioss.ServiceError:
-		if aliErr.StatusCode == http.StatusNotFound {
-			return true
-		}
+	aliErr, ok := serviceError(err)
+	if !ok {
+		return false
+	}
+	switch aliErr.Code {
+	case errCodeNoSuchKey, errCodeSymlinkTargetNotExist:
+		return true
+	}
+	return aliErr.StatusCode == http.StatusNotFound
+}
+
+// IsAccessDeniedErr returns true if OSS refused the request because of the credentials or the bucket
+// policy, either rejecting the access key or its signature, or denying it access.
+func (b *Bucket) IsAccessDeniedErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	if !ok {
+		return false
+	}
+	switch aliErr.Code {
+	case errCodeAccessDenied, errCodeInvalidAccessKeyID, errCodeSignatureDoesNotMatch:
+		return true
 	}
-	return false
+	return aliErr.StatusCode == http.StatusForbidden
 }
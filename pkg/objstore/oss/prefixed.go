@@ -0,0 +1,54 @@
+package oss
+
+import (
+	"strings"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// prefixTransform stores the keys of a prefixed view under its prefix, on top of the KeyTransform, if any,
+// of the Bucket it is a view of.
+type prefixTransform struct {
+	prefix string
+	next   KeyTransform
+}
+
+func (t prefixTransform) Physical(key string) string {
+	if t.next == nil {
+		return t.prefix + key
+	}
+	return t.next.Physical(t.prefix + key)
+}
+
+func (t prefixTransform) Logical(key string) (string, bool) {
+	if t.next != nil {
+		var ok bool
+		if key, ok = t.next.Logical(key); !ok {
+			return "", false
+		}
+	}
+	if !strings.HasPrefix(key, t.prefix) {
+		return "", false
+	}
+	return key[len(t.prefix):], true
+}
+
+// NewPrefixedBucket returns a view of b storing every object under the given prefix, which is treated as a
+// directory, e.g. to keep the blocks of several tenants in one OSS bucket. Keys passed to the view and
+// reported by its listings are relative to the prefix, objects outside of it are not visible. The view
+// shares the client, configuration, limits and metrics of b and closing it leaves b open. Key filters
+// still apply to the full OSS keys.
+func NewPrefixedBucket(b *Bucket, prefix string) *Bucket {
+	view := *b
+	view.credentials = nil
+	view.keys = withPrefix(b.keys, prefix)
+	return &view
+}
+
+// withPrefix returns the KeyTransform storing the keys under the directory prefix, then mapped by t.
+func withPrefix(t KeyTransform, prefix string) KeyTransform {
+	if prefix = strings.Trim(prefix, objstore.DirDelim); prefix == "" {
+		return t
+	}
+	return prefixTransform{prefix: prefix + objstore.DirDelim, next: t}
+}
@@ -0,0 +1,136 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// ParallelRangeConfig configures fetching large GetRange reads as several ranged GETs in parallel.
+type ParallelRangeConfig struct {
+	// Concurrency is the maximum number of chunks fetched at once. Values below 2 disable parallel reads.
+	Concurrency int `yaml:"concurrency"`
+	// ChunkSize is the size in bytes of each ranged GET. Only reads larger than a single chunk are split.
+	ChunkSize int64 `yaml:"chunk_size"`
+}
+
+func (c ParallelRangeConfig) validate() error {
+	if c.Concurrency < 0 || c.ChunkSize < 0 {
+		return errors.Errorf("invalid aliyun oss parallel_range: concurrency %d and chunk_size %d must not be negative", c.Concurrency, c.ChunkSize)
+	}
+	if c.Concurrency > 1 && c.ChunkSize == 0 {
+		return errors.New("aliyun oss parallel_range chunk_size must be set when concurrency is")
+	}
+	return nil
+}
+
+func (c ParallelRangeConfig) splits(length int64) bool {
+	return c.Concurrency > 1 && length > c.ChunkSize
+}
+
+// rangeChunk is one ranged GET of a parallel read. done is closed once data or err is set.
+type rangeChunk struct {
+	start, end int64
+	done       chan struct{}
+	data       []byte
+	err        error
+}
+
+// parallelRangeReader returns the bytes start..end of an object, both inclusive, fetched in chunks by
+// concurrent ranged GETs and handed out in order. At most Concurrency chunks are held in memory: a chunk
+// is only fetched once an earlier one was consumed, so a slow reader does not buffer the whole range.
+type parallelRangeReader struct {
+	chunks []*rangeChunk
+	cur    int
+	buf    []byte
+	// slots holds a token for every chunk fetched or being fetched but not yet consumed.
+	slots  chan struct{}
+	cancel context.CancelFunc
+}
+
+func (b *Bucket) getParallelRange(ctx context.Context, name string, start, end int64, opts []alioss.Option) io.ReadCloser {
+	cfg := b.config.ParallelRange
+	ctx, cancel := context.WithCancel(ctx)
+	r := &parallelRangeReader{slots: make(chan struct{}, cfg.Concurrency), cancel: cancel}
+	for off := start; off <= end; off += cfg.ChunkSize {
+		last := off + cfg.ChunkSize - 1
+		if last > end {
+			last = end
+		}
+		r.chunks = append(r.chunks, &rangeChunk{start: off, end: last, done: make(chan struct{})})
+	}
+
+	go func() {
+		for i, c := range r.chunks {
+			select {
+			case r.slots <- struct{}{}:
+			case <-ctx.Done():
+				for _, c := range r.chunks[i:] {
+					c.err = ctx.Err()
+					close(c.done)
+				}
+				return
+			}
+			go func(c *rangeChunk) {
+				defer close(c.done)
+				c.data, c.err = b.getChunk(ctx, name, c.start, c.end, opts)
+			}(c)
+		}
+	}()
+	return r
+}
+
+// getChunk fetches the bytes start..end of the object, retrying the ranged GET as a whole when it or
+// reading its body fails.
+func (b *Bucket) getChunk(ctx context.Context, name string, start, end int64, opts []alioss.Option) ([]byte, error) {
+	var data []byte
+	err := b.retry(ctx, func() error {
+		rc, err := b.bucket.GetObject(name, append(opts[:len(opts):len(opts)], alioss.Range(start, end))...)
+		if err != nil {
+			return err
+		}
+		defer runutil.CloseWithLogOnErr(b.logger, rc, "oss range chunk reader")
+
+		if data, err = ioutil.ReadAll(rc); err != nil {
+			return errors.Wrapf(err, "read range %d-%d of oss object %s", start, end, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != end-start+1 {
+		return nil, errors.Errorf("read range %d-%d of oss object %s: got %d bytes", start, end, name, len(data))
+	}
+	return data, nil
+}
+
+func (r *parallelRangeReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.cur == len(r.chunks) {
+			return 0, io.EOF
+		}
+		c := r.chunks[r.cur]
+		<-c.done
+		if c.err != nil {
+			return 0, c.err
+		}
+		r.buf, c.data = c.data, nil
+		r.cur++
+		<-r.slots
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close stops fetching further chunks. Chunks already requested are still read to the end by their
+// goroutine, as the SDK cannot cancel requests in flight, and then dropped.
+func (r *parallelRangeReader) Close() error {
+	r.cancel()
+	return nil
+}
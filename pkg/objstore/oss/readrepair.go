@@ -0,0 +1,53 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// getRepaired reads the whole object and checks it against the CRC64 computed by OSS, fetching it again
+// up to read_repair_attempts times on a mismatch.
+func (b *Bucket) getRepaired(ctx context.Context, name string, opts []alioss.Option) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		data, err := b.getVerified(ctx, name, opts)
+		if err == nil {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		if !IsChecksumMismatchErr(err) || attempt > b.config.ReadRepairAttempts {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		level.Warn(b.logger).Log("msg", "checksum mismatch reading object, fetching it again", "object", name, "attempt", attempt, "err", err)
+	}
+}
+
+func (b *Bucket) getVerified(ctx context.Context, name string, opts []alioss.Option) ([]byte, error) {
+	var res *alioss.GetObjectResult
+	err := b.retry(ctx, func() (err error) {
+		res, err = b.bucket.DoGetObject(&alioss.GetObjectRequest{ObjectKey: name}, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, res.Response.Body, "oss get object body")
+
+	data, err := ioutil.ReadAll(res.Response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read oss object %s", name)
+	}
+	// Gateways may not return the checksum, objects read from those are not verified.
+	if res.ClientCRC != nil && res.ServerCRC != 0 && res.ClientCRC.Sum64() != res.ServerCRC {
+		return nil, errors.Wrapf(errChecksumMismatch, "oss object %s has CRC64 %d instead of %d", name, res.ClientCRC.Sum64(), res.ServerCRC)
+	}
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package oss
+
+import (
+	"net/url"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces secrets in redacted configs and URLs.
+const redacted = "<redacted>"
+
+// Redacted returns a copy of the config with the access key secret, the security token and the password
+// of the proxy URL masked, to be logged or included in errors.
+func (c Config) Redacted() Config {
+	if c.AccessKeySecret != "" {
+		c.AccessKeySecret = redacted
+	}
+	if c.SecurityToken != "" {
+		c.SecurityToken = redacted
+	}
+	c.HTTPConfig.ProxyURL = redactURL(c.HTTPConfig.ProxyURL)
+	return c
+}
+
+// String returns the redacted config in YAML, so that formatting a config never reveals its secrets.
+func (c Config) String() string {
+	b, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return "<invalid aliyun oss config: " + err.Error() + ">"
+	}
+	return string(b)
+}
+
+// redactURL returns the URL with its password masked. URLs failing to parse are masked as a whole, as
+// their user info cannot be told apart.
+func redactURL(s string) string {
+	if s == "" {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
@@ -0,0 +1,97 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// requestContext returns the context of an operation started with ctx, which is done once the request
+// timeout elapsed if one is configured.
+func (b *Bucket) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.config.RequestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(b.config.RequestTimeout))
+}
+
+// cancelOnClose returns rc releasing the context of the operation returning it, as set up by
+// requestContext, once closed.
+func (b *Bucket) cancelOnClose(rc io.ReadCloser, cancel context.CancelFunc) io.ReadCloser {
+	if b.config.RequestTimeout <= 0 {
+		return rc
+	}
+	return cancelingReadCloser{ReadCloser: rc, cancel: cancel}
+}
+
+type cancelingReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r cancelingReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// requestTimeoutTransport fails requests taking longer than the timeout in total, body included. The SDK
+// cannot cancel requests, so without it an operation would wait for a hung request past its deadline.
+type requestTimeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t requestTimeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	parent := r.Context()
+	ctx, cancel := context.WithTimeout(parent, t.timeout)
+	resp, err := t.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, t.mapErr(parent, ctx, err)
+	}
+	resp.Body = &requestTimeoutBody{ReadCloser: resp.Body, t: t, parent: parent, ctx: ctx, cancel: cancel}
+	return resp, nil
+}
+
+// mapErr returns a requestTimeoutError if err was caused by the timeout rather than the caller.
+func (t requestTimeoutTransport) mapErr(parent, ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		return requestTimeoutError{timeout: t.timeout}
+	}
+	return err
+}
+
+type requestTimeoutBody struct {
+	io.ReadCloser
+	t           requestTimeoutTransport
+	parent, ctx context.Context
+	cancel      context.CancelFunc
+}
+
+func (b *requestTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.t.mapErr(b.parent, b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *requestTimeoutBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// requestTimeoutError is returned for requests exceeding the request timeout. The operation sending them
+// ran out of time as well, so it is not retried.
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e requestTimeoutError) Error() string {
+	return fmt.Sprintf("request not completed within request_timeout %s", e.timeout)
+}
+
+func (requestTimeoutError) Timeout() bool   { return true }
+func (requestTimeoutError) Temporary() bool { return false }
@@ -0,0 +1,65 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// restoreRequestXML is the request body of RestoreObject, which the SDK cannot send.
+type restoreRequestXML struct {
+	XMLName xml.Name `xml:"RestoreRequest"`
+	Days    int      `xml:"Days"`
+}
+
+// RestoreObject starts restoring an object of the Archive or ColdArchive storage class, making it readable
+// for the given number of days once restored. Restoring takes minutes to hours, during which reads keep
+// failing with an error satisfying IsInvalidObjectStateErr, so callers poll until reads succeed. Restoring
+// an object again while a restore is in progress is not an error, and restoring an already restored object
+// extends its restored period.
+func (b *Bucket) RestoreObject(ctx context.Context, name string, days int) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if days < 1 {
+		return errors.Errorf("invalid days %d to restore oss object %s", days, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := xml.Marshal(restoreRequestXML{Days: days})
+	if err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	// The SDK's RestoreObject sends no body, so restored objects would always be kept one day.
+	err = b.retry(ctx, func() error {
+		resp, err := b.client.Conn.Do("POST", b.name, name, map[string]interface{}{"restore": nil}, nil, bytes.NewReader(body), 0, nil)
+		if resp != nil {
+			defer runutil.CloseWithLogOnErr(b.logger, resp.Body, "oss restore object response body")
+		}
+		return err
+	})
+	if IsRestoreInProgressErr(err) {
+		err = nil
+	}
+	if err != nil {
+		err = withClockSkewHint(errors.Wrapf(err, "restore oss object %s", name))
+	}
+	b.audit(OpRestore, name, 0, start, err)
+	return err
+}
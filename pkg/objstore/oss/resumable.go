@@ -0,0 +1,163 @@
+package oss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// resumableSource returns the file to upload with a checkpoint if resumable uploads are enabled and r is
+// a file to be uploaded from its start. The checkpoint names the file by path, so other readers cannot
+// be resumed, and the options of an upload in progress cannot be changed, so neither can uploads setting
+// tags, user metadata or an explicit content type. The SDK's UploadFile sends no callbacks nor
+// preconditions, so uploads with those are not resumed either.
+func (b *Bucket) resumableSource(r io.Reader, p *uploadParams) (*os.File, bool) {
+	f, ok := r.(*os.File)
+	if !ok || !b.config.EnableResumableUpload || len(p.tags) > 0 || len(p.meta) > 0 || p.contentType != "" {
+		return nil, false
+	}
+	if p.callback != "" || p.ifMatch != "" || p.ifNoneMatch != "" || p.checkETag {
+		return nil, false
+	}
+	if off, err := f.Seek(0, io.SeekCurrent); err != nil || off != 0 {
+		return nil, false
+	}
+	return f, true
+}
+
+// uploadResumable uploads the file with the SDK's UploadFile, which records the multipart upload and its
+// completed parts in a checkpoint file in CheckpointDir. An attempt finding the checkpoint of the same,
+// unmodified file and object resumes the upload, sending only the parts missing, and the checkpoint is
+// removed once the upload completed. Failed attempts leave the multipart upload and checkpoint in place,
+// regardless of the multipart retention window. With CRC validation enabled, the CRC64 of the file is
+// returned.
+func (b *Bucket) uploadResumable(ctx context.Context, name string, f *os.File, size, partSize int64, p *uploadParams) (uint64, error) {
+	var crc uint64
+	if b.config.EnableCRCValidation {
+		var err error
+		if crc, err = seekableCRC64(f, size); err != nil {
+			return 0, errors.Wrap(err, "failed to checksum upload source")
+		}
+	}
+	file, err := filepath.Abs(f.Name())
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256([]byte(file + "\x00" + b.name + "/" + name))
+	checkpoint := filepath.Join(b.config.CheckpointDir, hex.EncodeToString(sum[:])+".cp")
+
+	// UploadFile cannot be cancelled, its requests are sent by a client bound to ctx instead.
+	bkt, err := b.newClient(contextTransport{ctx: ctx, next: b.transport})
+	if err != nil {
+		return 0, err
+	}
+	concurrency := b.config.UploadConcurrency
+	if concurrency == 0 {
+		concurrency = defaultUploadConcurrency
+	}
+	opts := append(p.options(), b.createOptions(name, p)...)
+	opts = append(opts, b.transferOptions()...)
+	opts = append(opts,
+		alioss.Checkpoint(true, checkpoint),
+		alioss.Routines(concurrency),
+		alioss.Progress(uploadProgress{ctx: ctx, b: b}),
+	)
+	err = b.retry(ctx, func() error {
+		if err := fixCheckpointTime(checkpoint); err != nil {
+			return errors.Wrapf(err, "read upload checkpoint %s", checkpoint)
+		}
+		return bkt.UploadFile(name, file, partSize, opts...)
+	})
+	if ctx.Err() != nil {
+		return 0, errors.Wrap(ctx.Err(), "resumable upload cancelled")
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to upload file")
+	}
+	return crc, nil
+}
+
+// contextTransport sends every request with the context of the operation, which the SDK does not pass.
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(r.WithContext(t.ctx))
+}
+
+// uploadProgress counts the bytes sent by UploadFile, which reads the file itself, and caps their
+// bandwidth to upload_bandwidth_limit. The SDK reports them while reading the request bodies, so waiting
+// for the limiter holds back the upload.
+type uploadProgress struct {
+	ctx context.Context
+	b   *Bucket
+}
+
+func (p uploadProgress) ProgressChanged(e *alioss.ProgressEvent) {
+	if e.EventType != alioss.TransferDataEvent || e.RwBytes <= 0 {
+		return
+	}
+	p.b.metrics.uploadedBytes.Add(float64(e.RwBytes))
+	if p.b.uploadLimiter == nil {
+		return
+	}
+	burst := int64(p.b.uploadLimiter.Burst())
+	for n := e.RwBytes; n > 0; n -= burst {
+		wait := n
+		if wait > burst {
+			wait = burst
+		}
+		if p.b.uploadLimiter.WaitN(p.ctx, int(wait)) != nil {
+			return
+		}
+	}
+}
+
+// fixCheckpointTime rewrites the file modification time of the checkpoint at path, if any, with an
+// explicit zero offset instead of Z. The SDK compares the time loaded from a checkpoint with that of the
+// file using !=, and only the former parses into the UTC location rather than the local one when the
+// local time zone is UTC, so the checkpoint would never be resumed.
+func fixCheckpointTime(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cp map[string]json.RawMessage
+	if json.Unmarshal(data, &cp) != nil {
+		// The SDK discards invalid checkpoints.
+		return nil
+	}
+	var stat map[string]json.RawMessage
+	if json.Unmarshal(cp["FileStat"], &stat) != nil {
+		return nil
+	}
+	var modified string
+	if json.Unmarshal(stat["LastModified"], &modified) != nil || !strings.HasSuffix(modified, "Z") {
+		return nil
+	}
+	if stat["LastModified"], err = json.Marshal(strings.TrimSuffix(modified, "Z") + "+00:00"); err != nil {
+		return err
+	}
+	if cp["FileStat"], err = json.Marshal(stat); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(cp); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,137 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+)
+
+// RetryConfig configures retrying failed idempotent requests: reads, listings, existence checks,
+// deletions, and object and part uploads, which are sent again from the start of their source. Requests
+// initiating and completing multipart uploads are not retried.
+type RetryConfig struct {
+	// MaxRetries is the number of times a failed request is retried. Zero disables retries.
+	MaxRetries int `yaml:"max_retries"`
+	// Backoff is the wait before the first retry, doubled for each further one. Defaults to 100ms. Each
+	// wait is randomized between half and all of it, so that clients failing together do not retry in step.
+	Backoff model.Duration `yaml:"backoff"`
+	// MaxBackoff caps the wait between retries. Defaults to 1m, or to Backoff if that is longer.
+	MaxBackoff model.Duration `yaml:"max_backoff"`
+}
+
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = time.Minute
+)
+
+// ShouldRetryFunc decides whether a request failing with err on the given attempt, starting at 1, is retried.
+type ShouldRetryFunc func(err error, attempt int) bool
+
+// WithShouldRetry makes the Bucket decide which failed requests are retried with f rather than with
+// IsRetryable and retry_invalid_object_state, e.g. to retry errors specific to an OSS compatible gateway.
+// f is only asked while retries are left: it cannot extend the number of retries set by the retry
+// configuration.
+func WithShouldRetry(f ShouldRetryFunc) Option {
+	return optionFunc(func(b *Bucket) {
+		b.shouldRetry = f
+	})
+}
+
+// IsRetryable returns true if err is likely transient, so that sending the same request again may succeed:
+// OSS server errors, throttling and request timeouts, and network errors.
+func IsRetryable(err error) bool {
+	if aliErr, ok := serviceError(err); ok {
+		return aliErr.StatusCode >= http.StatusInternalServerError || aliErr.StatusCode == http.StatusTooManyRequests ||
+			aliErr.Code == "RequestTimeout"
+	}
+	switch cause := errors.Cause(err).(type) {
+	case *url.Error:
+		return cause.Err != context.Canceled && cause.Err != context.DeadlineExceeded
+	case net.Error:
+		return true
+	}
+	return errors.Cause(err) == io.ErrUnexpectedEOF
+}
+
+// retry calls f until it succeeds, fails with an error not worth retrying or the retries are exhausted,
+// and returns its last error.
+func (b *Bucket) retry(ctx context.Context, f func() error) error {
+	return b.retryWith(ctx, b.config.Retry, f)
+}
+
+// retryList is retry for requests fetching a page of a listing.
+func (b *Bucket) retryList(ctx context.Context, f func() error) error {
+	if b.config.ListRetry.MaxRetries > 0 {
+		return b.retryWith(ctx, b.config.ListRetry, f)
+	}
+	return b.retry(ctx, f)
+}
+
+// retryUpload is retry for requests sending r, which is rewound to its current offset before each retry.
+func (b *Bucket) retryUpload(ctx context.Context, r io.Seeker, f func() error) error {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	sent := false
+	return b.retry(ctx, func() error {
+		if sent {
+			if _, err := r.Seek(pos, io.SeekStart); err != nil {
+				return errors.Wrap(err, "rewind upload source")
+			}
+		}
+		sent = true
+		return f()
+	})
+}
+
+func (b *Bucket) retryWith(ctx context.Context, cfg RetryConfig, f func() error) error {
+	backoff := time.Duration(cfg.Backoff)
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	maxBackoff := time.Duration(cfg.MaxBackoff)
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+		if backoff > maxBackoff {
+			maxBackoff = backoff
+		}
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	shouldRetry := b.shouldRetry
+	if shouldRetry == nil {
+		shouldRetry = func(err error, _ int) bool {
+			return IsRetryable(err) || (b.config.RetryInvalidObjectState && IsInvalidObjectStateErr(err))
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt > cfg.MaxRetries || !shouldRetry(err, attempt) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))):
+		}
+		backoff = nextBackoff(backoff, maxBackoff)
+	}
+}
+
+// nextBackoff returns backoff doubled, capped at max without overflowing.
+func nextBackoff(backoff, max time.Duration) time.Duration {
+	if backoff > max/2 {
+		return max
+	}
+	return backoff * 2
+}
@@ -0,0 +1,72 @@
+package oss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// Key of the user metadata holding the hex encoded SHA256 of the object content, sent as x-oss-meta-sha256.
+const sha256MetaKey = "sha256"
+
+// seekableSHA256 returns the hex encoded SHA256 of the remaining content of r, leaving r at its current offset.
+func seekableSHA256(r io.ReadSeeker) (string, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(pos, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifySHA256 downloads the object and checks its content against the SHA256 stored in its metadata
+// when it was uploaded with sha256_metadata enabled.
+func (b *Bucket) VerifySHA256(ctx context.Context, name string) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	var res *alioss.GetObjectResult
+	err = b.retry(ctx, func() (err error) {
+		res, err = b.bucket.DoGetObject(&alioss.GetObjectRequest{ObjectKey: name}, b.readOptions())
+		return err
+	})
+	if err != nil {
+		return withClockSkewHint(errors.Wrapf(err, "get oss object %s", name))
+	}
+	body := b.downloadReader(ctx, res.Response.Body)
+	defer runutil.CloseWithLogOnErr(b.logger, body, "oss get object body")
+
+	want := res.Response.Headers.Get(alioss.HTTPHeaderOssMetaPrefix + sha256MetaKey)
+	if want == "" {
+		return errors.Errorf("oss object %s has no sha256 metadata", name)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return errors.Wrapf(err, "read oss object %s", name)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return errors.Errorf("sha256 of oss object %s does not match its metadata: expected %s, got %s", name, want, got)
+	}
+	return nil
+}
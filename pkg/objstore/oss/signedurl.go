@@ -0,0 +1,106 @@
+package oss
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// MaxSignedURLExpiry is the longest validity accepted for signed URLs.
+const MaxSignedURLExpiry = 7 * 24 * time.Hour
+
+// ResponseHeaders overrides headers of the response to a signed download URL, e.g. to make browsers save
+// the object under a sensible file name. Empty fields leave the stored header of the object untouched.
+type ResponseHeaders struct {
+	ContentType        string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	CacheControl       string
+	Expires            string
+}
+
+func (h ResponseHeaders) options() ([]alioss.Option, error) {
+	var opts []alioss.Option
+	for _, o := range []struct {
+		name, value string
+		option      func(string) alioss.Option
+	}{
+		{name: "Content-Type", value: h.ContentType, option: alioss.ResponseContentType},
+		{name: "Content-Disposition", value: h.ContentDisposition, option: alioss.ResponseContentDisposition},
+		{name: "Content-Encoding", value: h.ContentEncoding, option: alioss.ResponseContentEncoding},
+		{name: "Content-Language", value: h.ContentLanguage, option: alioss.ResponseContentLanguage},
+		{name: "Cache-Control", value: h.CacheControl, option: alioss.ResponseCacheControl},
+		{name: "Expires", value: h.Expires, option: alioss.ResponseExpires},
+	} {
+		if o.value == "" {
+			continue
+		}
+		if strings.ContainsAny(o.value, "\r\n\x00") {
+			return nil, errors.Errorf("invalid %s response header override %q: contains control characters", o.name, o.value)
+		}
+		opts = append(opts, o.option(o.value))
+	}
+	// Both headers share the media type syntax: a value followed by optional parameters.
+	if h.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(h.ContentType); err != nil {
+			return nil, errors.Wrapf(err, "invalid Content-Type response header override %q", h.ContentType)
+		}
+	}
+	if h.ContentDisposition != "" {
+		if _, _, err := mime.ParseMediaType(h.ContentDisposition); err != nil {
+			return nil, errors.Wrapf(err, "invalid Content-Disposition response header override %q", h.ContentDisposition)
+		}
+	}
+	return opts, nil
+}
+
+// signedURLMethods maps the HTTP methods signed URLs can be issued for to those of the SDK.
+var signedURLMethods = map[string]alioss.HTTPMethod{
+	http.MethodGet: alioss.HTTPGet,
+	http.MethodPut: alioss.HTTPPut,
+}
+
+// SignedURL returns a URL allowing anyone holding it to send a request with the given method to the object
+// until the expiry elapses: GET to download the object, with the given headers overridden in the response,
+// or PUT to upload it. Uploads must be sent without a Content-Type header, as it is part of the signature.
+// The expiry is rounded down to whole seconds and must be between one second and MaxSignedURLExpiry.
+func (b *Bucket) SignedURL(ctx context.Context, name string, method string, expiry time.Duration, h ResponseHeaders) (string, error) {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if expiry < time.Second || expiry > MaxSignedURLExpiry {
+		return "", errors.Errorf("invalid signed url expiry %s: must be between 1s and %s", expiry, MaxSignedURLExpiry)
+	}
+	m, ok := signedURLMethods[method]
+	if !ok {
+		return "", errors.Errorf("unsupported signed url method %q: must be %s or %s", method, http.MethodGet, http.MethodPut)
+	}
+	opts, err := h.options()
+	if err != nil {
+		return "", err
+	}
+	if method != http.MethodGet {
+		if err := b.checkWritable(); err != nil {
+			return "", err
+		}
+	}
+	if len(opts) > 0 && method != http.MethodGet {
+		return "", errors.Errorf("response header overrides are only supported by %s signed urls", http.MethodGet)
+	}
+
+	u, err := b.bucket.SignURL(name, m, int64(expiry/time.Second), opts...)
+	if err != nil {
+		return "", errors.Wrapf(err, "sign url of oss object %s", name)
+	}
+	return u, nil
+}
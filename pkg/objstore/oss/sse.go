@@ -0,0 +1,47 @@
+package oss
+
+import (
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// Supported values of SSEConfig.Type.
+const (
+	SSEAES256 = "AES256"
+	SSEKMS    = "KMS"
+)
+
+// SSEConfig configures the server-side encryption of uploaded objects.
+type SSEConfig struct {
+	// Type is the encryption of uploaded objects, AES256 for keys managed by OSS or KMS for keys managed by
+	// KMS. Empty leaves objects encrypted as set by the default encryption of the bucket, if any.
+	Type string `yaml:"type"`
+	// KMSKeyID is the KMS key objects are encrypted with when Type is KMS. Empty uses the default KMS key
+	// OSS manages for the bucket.
+	KMSKeyID string `yaml:"kms_key_id"`
+}
+
+func (c SSEConfig) validate() error {
+	switch c.Type {
+	case "", SSEAES256, SSEKMS:
+	default:
+		return errors.Errorf("unsupported aliyun oss sse_config type %q", c.Type)
+	}
+	if c.KMSKeyID != "" && c.Type != SSEKMS {
+		return errors.Errorf("aliyun oss sse_config kms_key_id requires type %s", SSEKMS)
+	}
+	return nil
+}
+
+// options returns the SDK options of requests creating objects. Parts of multipart uploads are encrypted
+// as set when the upload was initiated and take no encryption options.
+func (c SSEConfig) options() []alioss.Option {
+	if c.Type == "" {
+		return nil
+	}
+	opts := []alioss.Option{alioss.ServerSideEncryption(c.Type)}
+	if c.KMSKeyID != "" {
+		opts = append(opts, alioss.ServerSideEncryptionKeyID(c.KMSKeyID))
+	}
+	return opts
+}
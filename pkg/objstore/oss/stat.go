@@ -0,0 +1,94 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// BucketStat holds bucket level storage statistics. They cover the whole bucket, regardless of the key
+// filters and key transform of the Bucket.
+type BucketStat struct {
+	// StorageBytes is the total size of the objects in the bucket.
+	StorageBytes int64
+	ObjectCount  int64
+	// MultipartUploadCount is the number of incomplete multipart uploads.
+	MultipartUploadCount int64
+
+	// FromListing is set when the endpoint does not support GetBucketStat, e.g. an OSS compatible gateway,
+	// and the statistics were summed up by listing the whole bucket instead. Note then explains that.
+	FromListing bool
+	Note        string
+}
+
+const bucketStatListingNote = "GetBucketStat is not supported by the endpoint, statistics were computed by listing the whole bucket " +
+	"and exclude the size of parts of incomplete multipart uploads"
+
+// Stat returns the storage statistics of the bucket. OSS computes them periodically, so recent changes
+// may not be reflected yet. Endpoints without GetBucketStat fall back to listing the whole bucket, which
+// takes a request per 1000 objects.
+func (b *Bucket) Stat(ctx context.Context) (BucketStat, error) {
+	if err := ctx.Err(); err != nil {
+		return BucketStat{}, err
+	}
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return BucketStat{}, err
+	}
+	defer release()
+	var res alioss.GetBucketStatResult
+	err = b.retry(ctx, func() (err error) {
+		res, err = b.client.GetBucketStat(b.name)
+		return err
+	})
+	if err == nil {
+		return BucketStat{StorageBytes: res.Storage, ObjectCount: res.ObjectCount, MultipartUploadCount: res.MultipartUploadCount}, nil
+	}
+	if !isUnsupportedErr(err) {
+		return BucketStat{}, withClockSkewHint(errors.Wrap(err, "get oss bucket stat"))
+	}
+	return b.listingStat(ctx)
+}
+
+// isUnsupportedErr returns true if the endpoint does not implement the requested operation.
+func isUnsupportedErr(err error) bool {
+	aliErr, ok := serviceError(err)
+	return ok && (aliErr.StatusCode == http.StatusNotImplemented || aliErr.StatusCode == http.StatusMethodNotAllowed)
+}
+
+func (b *Bucket) listingStat(ctx context.Context) (BucketStat, error) {
+	stat := BucketStat{FromListing: true, Note: bucketStatListingNote}
+	marker := alioss.Marker("")
+	for {
+		if err := ctx.Err(); err != nil {
+			return BucketStat{}, err
+		}
+		var objects alioss.ListObjectsResult
+		err := b.retryList(ctx, func() (err error) {
+			objects, err = b.bucket.ListObjects(marker, alioss.MaxKeys(1000))
+			return err
+		})
+		if err != nil {
+			return BucketStat{}, withClockSkewHint(errors.Wrap(err, "listing aliyun oss bucket failed"))
+		}
+		for _, o := range objects.Objects {
+			stat.StorageBytes += o.Size
+			stat.ObjectCount++
+		}
+		if !objects.IsTruncated {
+			break
+		}
+		marker = alioss.Marker(objects.NextMarker)
+	}
+
+	err := b.listMultipartUploads("", func(alioss.UncompletedUpload) error {
+		stat.MultipartUploadCount++
+		return ctx.Err()
+	})
+	if err != nil {
+		return BucketStat{}, withClockSkewHint(err)
+	}
+	return stat, nil
+}
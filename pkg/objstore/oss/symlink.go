@@ -0,0 +1,58 @@
+package oss
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SwapSymlink points the symlink alias to newTarget, creating it if needed, once newTarget was checked to
+// exist. OSS replaces the symlink in a single request, so readers of alias, which OSS resolves to its
+// target, see either the previous target or the new one. The target may still be deleted after the
+// check, leaving alias dangling.
+func (b *Bucket) SwapSymlink(ctx context.Context, alias, newTarget string) error {
+	alias, newTarget = b.physical(alias), b.physical(newTarget)
+	if err := b.checkKey(alias); err != nil {
+		return err
+	}
+	if err := b.checkKey(newTarget); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	err = b.swapSymlink(ctx, alias, newTarget)
+	err = withClockSkewHint(err)
+	b.audit(OpPutSymlink, alias, 0, start, err)
+	return err
+}
+
+func (b *Bucket) swapSymlink(ctx context.Context, alias, newTarget string) error {
+	var exists bool
+	err := b.retry(ctx, func() (err error) {
+		exists, err = b.bucket.IsObjectExist(newTarget)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "check symlink target %s", newTarget)
+	}
+	if !exists {
+		return errors.Errorf("symlink target %s does not exist", newTarget)
+	}
+	err = b.retry(ctx, func() error {
+		return b.bucket.PutSymlink(alias, newTarget)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "put oss symlink %s to %s", alias, newTarget)
+	}
+	b.forget(alias)
+	return nil
+}
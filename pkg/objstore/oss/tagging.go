@@ -0,0 +1,147 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// Limits OSS places on object tags.
+const (
+	MaxObjectTags     = 10
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+// validTagRune reports whether OSS accepts the character in tag keys and values: letters, digits, spaces
+// and the symbols + - = . _ : /.
+func validTagRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	switch r {
+	case ' ', '+', '-', '=', '.', '_', ':', '/':
+		return true
+	}
+	return false
+}
+
+func validateTag(what, s string, max int) error {
+	if n := utf8.RuneCountInString(s); n > max {
+		return errors.Errorf("invalid tag %s %q: longer than %d characters", what, s, max)
+	}
+	for _, r := range s {
+		if !validTagRune(r) {
+			return errors.Errorf("invalid tag %s %q: character %q is not allowed, only letters, digits, spaces and +-=._:/ are", what, s, r)
+		}
+	}
+	return nil
+}
+
+// objectTags validates the tags and returns them ordered by key.
+func objectTags(tags map[string]string) ([]alioss.Tag, error) {
+	if len(tags) > MaxObjectTags {
+		return nil, errors.Errorf("too many tags: %d, at most %d are allowed", len(tags), MaxObjectTags)
+	}
+	out := make([]alioss.Tag, 0, len(tags))
+	for k, v := range tags {
+		if k == "" {
+			return nil, errors.New("invalid tag: empty key")
+		}
+		if err := validateTag("key", k, MaxTagKeyLength); err != nil {
+			return nil, err
+		}
+		if err := validateTag("value", v, MaxTagValueLength); err != nil {
+			return nil, err
+		}
+		out = append(out, alioss.Tag{Key: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// PutObjectTagging replaces the tags of the object. Tags set by the Bucket itself, like the expiry tag of
+// UploadWithExpiry or the protected tag, are replaced as well unless included.
+func (b *Bucket) PutObjectTagging(ctx context.Context, name string, tags map[string]string) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t, err := objectTags(tags)
+	if err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	err = b.retry(ctx, func() error { return b.bucket.PutObjectTagging(name, alioss.Tagging{Tags: t}) })
+	if err != nil {
+		err = withClockSkewHint(errors.Wrapf(err, "put tags of oss object %s", name))
+	}
+	b.audit(OpPutTagging, name, 0, start, err)
+	return err
+}
+
+// GetObjectTagging returns the tags of the object.
+func (b *Bucket) GetObjectTagging(ctx context.Context, name string) (map[string]string, error) {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	var res alioss.GetObjectTaggingResult
+	err = b.retry(ctx, func() (err error) {
+		res, err = b.bucket.GetObjectTagging(name)
+		return err
+	})
+	if err != nil {
+		return nil, withClockSkewHint(errors.Wrapf(err, "get tags of oss object %s", name))
+	}
+	tags := make(map[string]string, len(res.Tags))
+	for _, t := range res.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// UploadWithTags is Upload setting the given tags on the object when it is created.
+func (b *Bucket) UploadWithTags(ctx context.Context, name string, r io.Reader, tags map[string]string) error {
+	key := b.physical(name)
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+	t, err := objectTags(tags)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := b.upload(ctx, name, r, &uploadParams{tags: t})
+	err = withClockSkewHint(err)
+	b.audit(OpUpload, key, n, start, err)
+	return err
+}
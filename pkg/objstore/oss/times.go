@@ -0,0 +1,54 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// Key of the user metadata an uploader may set to the creation time of the object, sent as
+// x-oss-meta-creation-time. Both RFC 3339 timestamps and Unix seconds are understood.
+const creationTimeMetaKey = "creation-time"
+
+// ObjectTimes holds the timestamps known of an object.
+type ObjectTimes struct {
+	// LastModified is the time the object was last written, as reported by OSS.
+	LastModified time.Time
+	// Created is the creation time stamped by the uploader in the object metadata. It is the zero time if
+	// the uploader did not set it.
+	Created time.Time
+}
+
+// Times returns the timestamps of the object, as reported by Attributes too. Unlike LastModified, which
+// changes whenever the object is rewritten, e.g. when its metadata is replaced, Created is kept by
+// uploaders across such rewrites.
+func (b *Bucket) Times(ctx context.Context, name string) (ObjectTimes, error) {
+	attrs, err := b.Attributes(ctx, name)
+	if err != nil {
+		return ObjectTimes{}, err
+	}
+	if attrs.LastModified.IsZero() {
+		return ObjectTimes{}, errors.Errorf("oss object meta %s lacks the Last-Modified header", b.physical(name))
+	}
+	return ObjectTimes{LastModified: attrs.LastModified, Created: attrs.Created}, nil
+}
+
+// parseCreationTime returns the creation time stamped in the object metadata, or the zero time if none is.
+func parseCreationTime(header http.Header) (time.Time, error) {
+	v := header.Get(alioss.HTTPHeaderOssMetaPrefix + creationTimeMetaKey)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf("parse %s metadata %q: not an RFC 3339 timestamp nor Unix seconds", creationTimeMetaKey, v)
+	}
+	return time.Unix(secs, 0), nil
+}
@@ -0,0 +1,182 @@
+package oss
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+)
+
+// Defaults of the HTTP transport settings, matching the transport of the SDK where it has them.
+const (
+	defaultIdleConnTimeout       = 50 * time.Second
+	defaultResponseHeaderTimeout = 60 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultExpectContinueTimeout = 1 * time.Second
+	defaultMaxIdleConns          = 100
+	defaultMaxIdleConnsPerHost   = 100
+)
+
+// HTTPConfig stores the configuration of the HTTP transport of the OSS client. Zero values of the timeouts
+// and idle connection limits keep their defaults.
+type HTTPConfig struct {
+	// EnableHTTP2 negotiates HTTP/2 with HTTPS endpoints, multiplexing concurrent requests such as the many
+	// small range reads of the store gateway over fewer connections. HTTP/1.1 is used by default.
+	EnableHTTP2 bool `yaml:"enable_http2"`
+	// MaxConnsPerHost limits the connections to the endpoint, zero means no limit. With HTTP/2, requests are
+	// multiplexed as streams over these connections, up to the concurrent streams allowed by the server on
+	// each, and only additional concurrent requests open new connections.
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+	// IdleConnTimeout closes connections idle for longer, 50s by default.
+	IdleConnTimeout model.Duration `yaml:"idle_conn_timeout"`
+	// ResponseHeaderTimeout fails requests whose response headers take longer to arrive, 60s by default.
+	ResponseHeaderTimeout model.Duration `yaml:"response_header_timeout"`
+	// TLSHandshakeTimeout fails connections whose TLS handshake takes longer, 10s by default.
+	TLSHandshakeTimeout model.Duration `yaml:"tls_handshake_timeout"`
+	// ExpectContinueTimeout is the time to wait for the server to accept the body of requests sent with
+	// Expect: 100-continue, 1s by default.
+	ExpectContinueTimeout model.Duration `yaml:"expect_continue_timeout"`
+	// MaxIdleConns limits the idle connections kept open, 100 by default.
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// MaxIdleConnsPerHost limits the idle connections kept open to the endpoint, 100 by default. Raising it
+	// up to the number of concurrent requests, e.g. of the store gateway, avoids reconnecting after bursts.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	// ProxyURL is the URL of the HTTP proxy requests are sent through, including those fetching RAM role
+	// credentials from the ECS metadata service. When unset, the proxy is taken from the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL string `yaml:"proxy_url"`
+	// InsecureSkipVerify disables the verification of the certificate of HTTPS endpoints, e.g. of private
+	// deployments using self-signed certificates. It is ignored when TLSConfig sets a CA file.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// TLSConfig configures the verification of the certificate of HTTPS endpoints.
+	TLSConfig TLSConfig `yaml:"tls_config"`
+}
+
+// TLSConfig configures the verification of the certificate of HTTPS endpoints.
+type TLSConfig struct {
+	// CAFile is the path of a PEM bundle of the certificate authorities trusted instead of the system ones.
+	CAFile string `yaml:"ca_file"`
+}
+
+func (c HTTPConfig) validate() error {
+	for name, v := range map[string]int64{
+		"max_conns_per_host":      int64(c.MaxConnsPerHost),
+		"idle_conn_timeout":       int64(c.IdleConnTimeout),
+		"response_header_timeout": int64(c.ResponseHeaderTimeout),
+		"tls_handshake_timeout":   int64(c.TLSHandshakeTimeout),
+		"expect_continue_timeout": int64(c.ExpectContinueTimeout),
+		"max_idle_conns":          int64(c.MaxIdleConns),
+		"max_idle_conns_per_host": int64(c.MaxIdleConnsPerHost),
+	} {
+		if v < 0 {
+			return errors.Errorf("invalid aliyun oss http_config.%s: must not be negative", name)
+		}
+	}
+	if c.ProxyURL != "" {
+		// The errors leave out the URL, or mask its password, as it may hold the credentials of the proxy.
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			if uerr, ok := err.(*url.Error); ok {
+				err = uerr.Err
+			}
+			return errors.Wrap(err, "invalid aliyun oss http_config.proxy_url")
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return errors.Errorf("invalid aliyun oss http_config.proxy_url %q: must be an absolute http or https URL", redactURL(c.ProxyURL))
+		}
+	}
+	return nil
+}
+
+// tlsClientConfig returns the TLS configuration of the transport, or nil to use the default one.
+func (c HTTPConfig) tlsClientConfig() (*tls.Config, error) {
+	switch {
+	case c.TLSConfig.CAFile != "":
+		b, err := ioutil.ReadFile(c.TLSConfig.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read aliyun oss http_config.tls_config.ca_file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.Errorf("no PEM certificates found in aliyun oss http_config.tls_config.ca_file %s", c.TLSConfig.CAFile)
+		}
+		return &tls.Config{RootCAs: pool}, nil
+	case c.InsecureSkipVerify:
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	return nil, nil
+}
+
+// proxy returns the function choosing the proxy of requests, from the proxy URL or the environment.
+func (c HTTPConfig) proxy() func(*http.Request) (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	// The URL was checked by validate.
+	u, _ := url.Parse(c.ProxyURL)
+	return http.ProxyURL(u)
+}
+
+// proxyEnvironment reports whether a proxy is set in the environment. The SDK's default transport ignores it.
+func proxyEnvironment() bool {
+	for _, k := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		if os.Getenv(k) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTPTransport returns the transport configured by c, or nil if the SDK's default transport is used.
+func newHTTPTransport(c HTTPConfig) (*http.Transport, error) {
+	if c == (HTTPConfig{}) && !proxyEnvironment() {
+		return nil, nil
+	}
+	return httpTransport(c)
+}
+
+// httpTransport returns the transport configured by c, even if it is the SDK's default one.
+func httpTransport(c HTTPConfig) (*http.Transport, error) {
+	tlsConfig, err := c.tlsClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:           c.proxy(),
+		TLSClientConfig: tlsConfig,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          intOr(c.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   intOr(c.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		IdleConnTimeout:       durationOr(c.IdleConnTimeout, defaultIdleConnTimeout),
+		ResponseHeaderTimeout: durationOr(c.ResponseHeaderTimeout, defaultResponseHeaderTimeout),
+		TLSHandshakeTimeout:   durationOr(c.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ExpectContinueTimeout: durationOr(c.ExpectContinueTimeout, defaultExpectContinueTimeout),
+		// Setting a custom dialer disables HTTP/2 unless it is explicitly asked for.
+		ForceAttemptHTTP2: c.EnableHTTP2,
+	}, nil
+}
+
+func durationOr(d model.Duration, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return time.Duration(d)
+}
+
+func intOr(n, def int) int {
+	if n == 0 {
+		return def
+	}
+	return n
+}
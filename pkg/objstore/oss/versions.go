@@ -0,0 +1,191 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	alioss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// objectVersion is an entry of a version listing, either an object version or a delete marker.
+type objectVersion struct {
+	key, versionID         string
+	isLatest, deleteMarker bool
+	modTime                int64
+}
+
+// IterVersions calls f for every version of the objects with the given prefix, including delete markers, in
+// key order and from the newest to the oldest version of each key. Delete markers are passed with
+// isDeleteMarker set; they hold no content and mark the object as deleted when they are the latest version.
+// Versions of keys rejected by the configured key filters are skipped. Pages exceeding
+// max_list_buffer_objects are passed unsorted, see MaxListBufferObjects.
+func (b *Bucket) IterVersions(ctx context.Context, prefix string, f func(key, versionID string, isLatest, isDeleteMarker bool) error) error {
+	prefix = b.physical(prefix)
+	pageOpts := []alioss.Option{alioss.Prefix(prefix)}
+	if n := b.config.MaxListBufferObjects; n > 0 {
+		pageOpts = append(pageOpts, alioss.MaxKeys(n))
+	}
+	opts := pageOpts
+	for {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "context closed while iterating bucket versions")
+		}
+		var res alioss.ListObjectVersionsResult
+		// Like Iter, a slot is held per page only, so that f may use the Bucket.
+		release, err := b.acquire(ctx)
+		if err != nil {
+			return errors.Wrap(err, "context closed while iterating bucket versions")
+		}
+		err = b.retryList(ctx, func() (err error) {
+			res, err = b.bucket.ListObjectVersions(opts...)
+			return err
+		})
+		release()
+		if err != nil {
+			return withClockSkewHint(errors.Wrap(err, "listing aliyun oss bucket versions failed"))
+		}
+
+		if n, max := len(res.ObjectVersions)+len(res.ObjectDeleteMarkers), b.config.MaxListBufferObjects; max > 0 && n > max {
+			if err := b.streamVersions(res, f); err != nil {
+				return err
+			}
+		} else {
+			for _, v := range mergeVersions(res) {
+				if err := b.callVersion(v, f); err != nil {
+					return err
+				}
+			}
+		}
+		if !res.IsTruncated {
+			return nil
+		}
+		opts = append(pageOpts[:len(pageOpts):len(pageOpts)],
+			alioss.KeyMarker(res.NextKeyMarker),
+			alioss.VersionIdMarker(res.NextVersionIdMarker),
+		)
+	}
+}
+
+// mergeVersions merges the versions and delete markers of the page, which OSS returns in separate lists,
+// back into listing order. OSS reports modification times to the second, so versions written within the
+// same second are ordered by whether they are the latest one, and otherwise keep the order OSS returned them in.
+func mergeVersions(res alioss.ListObjectVersionsResult) []objectVersion {
+	versions := make([]objectVersion, 0, len(res.ObjectVersions)+len(res.ObjectDeleteMarkers))
+	for _, v := range res.ObjectVersions {
+		versions = append(versions, objectVersion{key: v.Key, versionID: v.VersionId, isLatest: v.IsLatest, modTime: v.LastModified.UnixNano()})
+	}
+	for _, m := range res.ObjectDeleteMarkers {
+		versions = append(versions, objectVersion{key: m.Key, versionID: m.VersionId, isLatest: m.IsLatest, deleteMarker: true, modTime: m.LastModified.UnixNano()})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].key != versions[j].key {
+			return versions[i].key < versions[j].key
+		}
+		if versions[i].modTime != versions[j].modTime {
+			return versions[i].modTime > versions[j].modTime
+		}
+		return versions[i].isLatest && !versions[j].isLatest
+	})
+	return versions
+}
+
+// streamVersions calls f for the versions and then the delete markers of the page as OSS returned them,
+// without merging them.
+func (b *Bucket) streamVersions(res alioss.ListObjectVersionsResult, f func(key, versionID string, isLatest, isDeleteMarker bool) error) error {
+	for _, v := range res.ObjectVersions {
+		if err := b.callVersion(objectVersion{key: v.Key, versionID: v.VersionId, isLatest: v.IsLatest}, f); err != nil {
+			return err
+		}
+	}
+	for _, m := range res.ObjectDeleteMarkers {
+		if err := b.callVersion(objectVersion{key: m.Key, versionID: m.VersionId, isLatest: m.IsLatest, deleteMarker: true}, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callVersion calls f for the version unless its key is filtered out or outside of the Bucket's keys.
+func (b *Bucket) callVersion(v objectVersion, f func(key, versionID string, isLatest, isDeleteMarker bool) error) error {
+	if !b.keyAllowed(v.key) {
+		return nil
+	}
+	key, ok := b.logical(v.key)
+	if !ok {
+		return nil
+	}
+	if err := f(key, v.versionID, v.isLatest, v.deleteMarker); err != nil {
+		return errors.Wrapf(err, "callback func invoke for version %s of object %s failed", v.versionID, key)
+	}
+	return nil
+}
+
+// GetVersion returns a reader for the given version of the object, as passed to IterVersions. Once ctx is
+// done, reading fails with its error. Versions are only kept with versioning enabled on the bucket.
+func (b *Bucket) GetVersion(ctx context.Context, name, versionID string) (io.ReadCloser, error) {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return nil, err
+	}
+	if versionID == "" {
+		return nil, errors.Errorf("no version of oss object %s given", name)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser
+	err = b.retry(ctx, func() (err error) {
+		rc, err = b.bucket.GetObject(name, append(b.readOptions(), alioss.VersionId(versionID))...)
+		return err
+	})
+	if err != nil {
+		release()
+		return nil, withClockSkewHint(errors.Wrapf(err, "get version %s of oss object %s", versionID, name))
+	}
+	return b.releaseOnClose(b.downloadReader(ctx, rc), release), nil
+}
+
+// DeleteVersion permanently removes the given version of the object, as passed to IterVersions, unlike
+// Delete which only adds a delete marker on buckets with versioning enabled. Removing the latest version
+// makes the previous one the current object. Deleting a missing version is not an error.
+func (b *Bucket) DeleteVersion(ctx context.Context, name, versionID string) error {
+	name = b.physical(name)
+	if err := b.checkKey(name); err != nil {
+		return err
+	}
+	if versionID == "" {
+		return errors.Errorf("no version of oss object %s given", name)
+	}
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	if err := b.checkProtected(ctx, name, alioss.VersionId(versionID)); err != nil {
+		b.audit(OpDelete, name, 0, start, err)
+		return err
+	}
+	err = b.retry(ctx, func() error { return b.bucket.DeleteObject(name, alioss.VersionId(versionID)) })
+	b.forget(name)
+	if err != nil {
+		err = withClockSkewHint(errors.Wrapf(err, "delete version %s of oss object", versionID))
+	}
+	b.audit(OpDelete, name, 0, start, err)
+	return err
+}
@@ -83,15 +83,18 @@ func (c *Container) Name() string {
 
 // Iter calls f for each entry in the given directory. The argument to f is the full
 // object name including the prefix of the inspected directory.
-func (c *Container) Iter(ctx context.Context, dir string, f func(string) error) error {
+func (c *Container) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
 	// Ensure the object name actually ends with a dir suffix. Otherwise we'll just iterate the
 	// object itself as one prefix item.
 	if dir != "" {
 		dir = strings.TrimSuffix(dir, DirDelim) + DirDelim
 	}
 
-	options := &objects.ListOpts{Full: false, Prefix: dir, Delimiter: DirDelim}
-	return objects.List(c.client, c.name, options).EachPage(func(page pagination.Page) (bool, error) {
+	listOpts := &objects.ListOpts{Full: false, Prefix: dir, Delimiter: DirDelim}
+	if objstore.ApplyIterOptions(options...).Recursive {
+		listOpts.Delimiter = ""
+	}
+	return objects.List(c.client, c.name, listOpts).EachPage(func(page pagination.Page) (bool, error) {
 		objectNames, err := objects.ExtractNames(page)
 		if err != nil {
 			return false, err